@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kelindar/genny/parse"
+	"gopkg.in/yaml.v2"
+)
+
+// configFiles are the files looked up by build when no config is specified.
+var configFiles = []string{"genny.yaml", "genny.yml", "genny.json"}
+
+// config describes every file generated by the build command.
+//
+//     targets:
+//       - in: queue_generic.go
+//         out: gen-queue.go
+//         pkg: queue
+//         types: "Generic=string,int"
+//
+// The groups are defined for every target, before the groups of -group.
+type config struct {
+	Groups  []string `yaml:"groups" json:"groups"`
+	Targets []target `yaml:"targets" json:"targets"`
+}
+
+// target describes a single file to generate, with the same settings as
+// the flags of the gen command. Paths are relative to the config file. When
+// in is a template directory, out is the directory the files are written to.
+type target struct {
+	In      string   `yaml:"in" json:"in"`
+	Out     string   `yaml:"out" json:"out"`
+	Pkg     string   `yaml:"pkg" json:"pkg"`
+	Imports []string `yaml:"imports" json:"imports"`
+	Tag     string   `yaml:"tag" json:"tag"`
+	Header  string   `yaml:"header" json:"header"`
+	Types   typeArgs `yaml:"types" json:"types"`
+	Engine  string   `yaml:"engine" json:"engine"`
+	Check   bool     `yaml:"check" json:"check"`
+	Tests   bool     `yaml:"tests" json:"tests"`
+	Source  bool     `yaml:"source" json:"source"`
+	Split   bool     `yaml:"split" json:"split"`
+}
+
+// typeArgs are the type strings of a target, which are either a single
+// string or a list of strings, like the arguments of the gen command.
+type typeArgs []string
+
+// UnmarshalYAML reads either a string or a list of strings.
+func (a *typeArgs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var arg string
+	if err := unmarshal(&arg); err == nil {
+		*a = typeArgs{arg}
+		return nil
+	}
+	return unmarshal((*[]string)(a))
+}
+
+// UnmarshalJSON reads either a string or a list of strings.
+func (a *typeArgs) UnmarshalJSON(b []byte) error {
+	var arg string
+	if err := json.Unmarshal(b, &arg); err == nil {
+		*a = typeArgs{arg}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// loadConfig reads the config file, which is either YAML or JSON depending
+// on its extension.
+func loadConfig(fileName string) (*config, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var c config
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		err = json.Unmarshal(b, &c)
+	} else {
+		err = yaml.UnmarshalStrict(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return &c, nil
+}
+
+// findConfig gets the config file to use, looking for one of the default
+// names in the current directory if none is specified.
+func findConfig(fileName string) (string, error) {
+	if fileName != "" {
+		return fileName, nil
+	}
+	for _, name := range configFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", errors.New("no config file found, expected one of " + strings.Join(configFiles, ", "))
+}
+
+// build generates (or verifies) every target of the config file and reports
+// the outcome of each. The defaults apply to targets which do not override
+// them.
+func build(fileName string, defaults options) (int, error) {
+	fileName, err := findConfig(fileName)
+	if err != nil {
+		return exitcodeInvalidArgs, err
+	}
+	c, err := loadConfig(fileName)
+	if err != nil {
+		return exitcodeInvalidArgs, err
+	}
+
+	defaults.groups = append(append([]string(nil), c.Groups...), defaults.groups...)
+	dir, failed, stale := filepath.Dir(fileName), 0, 0
+	for i, t := range c.Targets {
+		name := t.Out
+		if name == "" {
+			name = fmt.Sprintf("target %d", i+1)
+		}
+
+		if code, err := t.generate(dir, defaults); err != nil {
+			failed++
+			if code == exitcodeStale {
+				stale++
+			}
+			if defaults.json {
+				report(os.Stderr, true, code, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "FAIL\t%s: %v\n", name, err)
+			}
+			continue
+		}
+		if !defaults.json {
+			fmt.Fprintf(os.Stderr, "ok\t%s\n", name)
+		}
+	}
+
+	if failed > 0 && failed == stale {
+		return exitcodeStale, fmt.Errorf("%d of %d targets are out of date", stale, len(c.Targets))
+	}
+	if failed > 0 {
+		return exitcodeBuildFailed, fmt.Errorf("%d of %d targets failed", failed, len(c.Targets))
+	}
+	return 0, nil
+}
+
+// generate generates the target, resolving its paths relative to dir.
+func (t target) generate(dir string, defaults options) (int, error) {
+	if t.In == "" || t.Out == "" || len(t.Types) == 0 {
+		return exitcodeInvalidArgs, errors.New("in, out and types are required")
+	}
+
+	opts := defaults
+	opts.out = filepath.Join(dir, t.Out)
+	opts.pkgName, opts.tag, opts.imports = t.Pkg, t.Tag, t.Imports
+	if t.Header != "" {
+		opts.header = t.Header
+	}
+	opts.check = opts.check || t.Check
+	opts.tests = opts.tests || t.Tests
+	opts.source = opts.source || t.Source
+	opts.split = opts.split || t.Split
+	opts.types = t.Types
+	if t.Engine != "" {
+		engine, err := parse.ParseEngine(t.Engine)
+		if err != nil {
+			return exitcodeInvalidArgs, err
+		}
+		opts.engine = engine
+	}
+
+	typeSets, err := expandTypes(opts.groups, t.Types, opts.split)
+	if err != nil {
+		return exitcodeInvalidTypeSet, err
+	}
+
+	filename := filepath.Join(dir, t.In)
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return generatePackage(filename, typeSets, opts)
+	}
+	template, err := readTemplate(t.In, dir)
+	if err != nil {
+		return exitcodeSourceFileInvalid, err
+	}
+	opts.version = template.Version
+
+	return generate(template.Name, bytes.NewReader(template.Source), typeSets, opts)
+}
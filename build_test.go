@@ -0,0 +1,258 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const queueTemplate = `package queue
+
+import "github.com/kelindar/genny/generic"
+
+type Something generic.Type
+
+// SomethingQueue is a queue of Somethings.
+type SomethingQueue struct {
+	items []Something
+}
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuildYAML(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"queue.go": queueTemplate,
+		"genny.yaml": `
+groups:
+  - "SMALL=int,string"
+targets:
+  - in: queue.go
+    out: gen/int_queue.go
+    pkg: gen
+    types: "Something=SMALL"
+  - in: queue.go
+    out: float_queue.go
+    engine: types
+    header: "Code generated by queuegen for {{.Types}}. DO NOT EDIT."
+    types:
+      - "Something=float64"
+      - "Something=bool"
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	code, err := build(filepath.Join(dir, "genny.yaml"), options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "gen", "int_queue.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "package gen")
+		assert.Contains(t, string(b), "type IntQueue struct")
+		assert.Contains(t, string(b), "type StringQueue struct")
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "float_queue.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "// Code generated by queuegen for Something=float64; Something=bool. DO NOT EDIT.\n")
+		assert.Contains(t, string(b), "type Float64Queue struct")
+		assert.Contains(t, string(b), "type BoolQueue struct")
+	}
+}
+
+func TestBuildJSONFailures(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"queue.go": queueTemplate,
+		"genny.json": `{"targets": [
+			{"in": "queue.go", "out": "int_queue.go", "types": "Something=int"},
+			{"in": "missing.go", "out": "missing_queue.go", "types": "Something=int"},
+			{"in": "queue.go", "out": "bad_queue.go", "types": "Other=int"}
+		]}`,
+	})
+	defer os.RemoveAll(dir)
+
+	code, err := build(filepath.Join(dir, "genny.json"), options{})
+	assert.EqualError(t, err, "2 of 3 targets failed")
+	assert.Equal(t, exitcodeBuildFailed, code)
+
+	_, err = os.Stat(filepath.Join(dir, "int_queue.go"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "bad_queue.go"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBuildInvalidConfig(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"genny.yaml": "targets:\n  - input: queue.go\n",
+	})
+	defer os.RemoveAll(dir)
+
+	code, err := build(filepath.Join(dir, "genny.yaml"), options{})
+	assert.Error(t, err)
+	assert.Equal(t, exitcodeInvalidArgs, code)
+}
+
+func TestBuildPackage(t *testing.T) {
+	files := map[string]string{
+		"genny.yaml": `
+targets:
+  - in: tree
+    out: gen
+    pkg: gen
+    types: "Item=int"
+`,
+	}
+	for _, name := range []string{"tree.go", "iter.go"} {
+		b, err := ioutil.ReadFile(filepath.Join("parse", "test", "package", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Join("tree", name)] = string(b)
+	}
+	dir := writeFiles(t, files)
+	defer os.RemoveAll(dir)
+
+	code, err := build(filepath.Join(dir, "genny.yaml"), options{check: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "gen", "iter.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "package gen")
+		assert.Contains(t, string(b), "func (t *IntTree) EachInt(fn func(int))")
+	}
+	_, err = os.Stat(filepath.Join(dir, "gen", "tree.go"))
+	assert.NoError(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"queue.go": queueTemplate,
+		"genny.yaml": `
+targets:
+  - in: queue.go
+    out: int_queue.go
+    types: "Something=int"
+`,
+	})
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "genny.yaml")
+
+	code, err := build(config, options{verify: true})
+	assert.EqualError(t, err, "1 of 1 targets are out of date")
+	assert.Equal(t, exitcodeStale, code)
+	_, err = os.Stat(filepath.Join(dir, "int_queue.go"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = build(config, options{})
+	assert.NoError(t, err)
+	code, err = build(config, options{verify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(queueTemplate+"\n// Len is new.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, err = build(config, options{verify: true})
+	assert.Error(t, err)
+	assert.Equal(t, exitcodeStale, code)
+}
+
+func TestBuildSplit(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"queue.go": queueTemplate,
+		"genny.yaml": `
+groups:
+  - "SMALL=int,string"
+targets:
+  - in: queue.go
+    out: gen/queue.go
+    pkg: gen
+    split: true
+    source: true
+    types:
+      - "Something=SMALL"
+      - "Something=map[string]int"
+`,
+	})
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "genny.yaml")
+
+	code, err := build(config, options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	for name, typeName := range map[string]string{
+		"queue_int.go":          "IntQueue",
+		"queue_string.go":       "StringQueue",
+		"queue_mapstringint.go": "MapStringIntQueue",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "gen", name))
+		if assert.NoError(t, err, name) {
+			assert.Contains(t, string(b), "type "+typeName+" struct", name)
+			assert.Contains(t, string(b), "package gen", name)
+		}
+	}
+	_, err = os.Stat(filepath.Join(dir, "gen", "queue.go"))
+	assert.True(t, os.IsNotExist(err))
+
+	// every file records its own type set, so that it is regenerated alone
+	b, err := ioutil.ReadFile(filepath.Join(dir, "gen", "queue_string.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "// genny:source -types=\"Something=string\"\n")
+		assert.NotContains(t, string(b), "genny:source -group")
+	}
+	code, err = regen([]string{filepath.Join(dir, "gen", "queue_string.go")}, options{verify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	code, err = build(config, options{verify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	if err := ioutil.WriteFile(filepath.Join(dir, "queue.go"), []byte(queueTemplate+"\n// Len is new.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, err = build(config, options{verify: true})
+	assert.Error(t, err)
+	assert.Equal(t, exitcodeStale, code)
+
+	// the type sets are only expanded while generating
+	typeSets, err := expandTypes(nil, []string{"Key=BUILTINS Value=BUILTINS"}, true)
+	assert.NoError(t, err)
+	assert.Nil(t, typeSets)
+	_, err = expandTypes(nil, []string{"Key=int Key=int"}, true)
+	assert.Error(t, err)
+
+	// type sets which would overwrite each other are rejected up front
+	out := filepath.Join(dir, "dup", "queue.go")
+	code, err = generate("queue.go", strings.NewReader(queueTemplate), nil, options{out: out, split: true, types: []string{"Something=*int,int"}})
+	assert.EqualError(t, err, "the type sets \"Something=*int\" and \"Something=int\" would both be written to "+filepath.Join(dir, "dup", "queue_int.go"))
+	assert.Equal(t, exitcodeInvalidTypeSet, code)
+	_, err = os.Stat(filepath.Join(dir, "dup"))
+	assert.True(t, os.IsNotExist(err))
+
+	// a stale file does not hide a later type set which cannot be generated
+	out = filepath.Join(dir, "gen", "queue.go")
+	code, err = generate("queue.go", strings.NewReader(queueTemplate), nil, options{out: out, split: true, verify: true, types: []string{"Something=int,1"}})
+	assert.Error(t, err)
+	assert.Equal(t, exitcodeGenFailed, code)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+
+	"github.com/kelindar/genny/parse"
+)
+
+// exitcodeCategories names the exit codes, which serve as the categories of
+// the diagnostics.
+var exitcodeCategories = map[int]string{
+	exitcodeInvalidArgs:       "invalid-args",
+	exitcodeInvalidTypeSet:    "invalid-type-set",
+	exitcodeStdinFailed:       "stdin-failed",
+	exitcodeGenFailed:         "gen-failed",
+	exitcodeGetFailed:         "get-failed",
+	exitcodeSourceFileInvalid: "source-file-invalid",
+	exitcodeDestFileFailed:    "dest-file-failed",
+	exitcodeInternalError:     "internal-error",
+	exitcodeCheckFailed:       "check-failed",
+	exitcodeConstraintFailed:  "constraint-failed",
+	exitcodeBuildFailed:       "build-failed",
+	exitcodeStale:             "stale",
+}
+
+// diagnostic is a machine readable description of an error, written as a
+// single line of JSON when -json is specified.
+type diagnostic struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Generic  string `json:"generic,omitempty"`
+	Specific string `json:"specific,omitempty"`
+	Message  string `json:"message"`
+	Category string `json:"category"`
+	Code     int    `json:"code"`
+}
+
+// staleError represents a generated file which is out of date.
+type staleError struct {
+	FileName string
+}
+
+// Error gets a human readable string describing this error.
+func (e staleError) Error() string {
+	return e.FileName + " is out of date"
+}
+
+// newDiagnostic describes the error which caused genny to exit with the code,
+// taking the location and the types involved from the errors of the parse
+// package where available.
+func newDiagnostic(code int, err error) diagnostic {
+	d := diagnostic{
+		Severity: "error",
+		Message:  err.Error(),
+		Category: exitcodeCategories[code],
+		Code:     code,
+	}
+
+	var (
+		missing    *parse.MissingSpecificTypeError
+		source     *parse.SourceError
+		imports    *parse.ImportsError
+		typeCheck  *parse.TypeCheckError
+		constraint *parse.ConstraintError
+		stale      *staleError
+	)
+	switch {
+	case errors.As(err, &missing):
+		d.setPosition(missing.Pos)
+		d.Generic = missing.GenericType
+	case errors.As(err, &source):
+		d.setPosition(source.Pos)
+	case errors.As(err, &imports):
+		d.setPosition(imports.Pos)
+	case errors.As(err, &typeCheck):
+		d.setPosition(typeCheck.Pos)
+		d.Generic, d.Specific = typeCheck.Generic, typeCheck.Specific
+		if typeErr, ok := typeCheck.Err.(types.Error); ok {
+			d.Message = typeErr.Msg
+		}
+	case errors.As(err, &constraint):
+		d.setPosition(constraint.Pos)
+		d.Generic, d.Specific = constraint.Generic, constraint.Specific
+	case errors.As(err, &stale):
+		d.File = stale.FileName
+	}
+	return d
+}
+
+// setPosition sets the location of the diagnostic.
+func (d *diagnostic) setPosition(pos token.Position) {
+	d.File, d.Line, d.Column = pos.Filename, pos.Line, pos.Column
+}
+
+// report writes the error either as a diagnostic or as plain text.
+func report(w io.Writer, asJSON bool, code int, err error) {
+	if !asJSON {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+
+	b, _ := json.Marshal(newDiagnostic(code, err))
+	fmt.Fprintf(w, "%s\n", b)
+}
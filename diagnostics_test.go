@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/token"
+	"os"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostic(t *testing.T) {
+	err := &parse.ConstraintError{
+		Generic:    "NumberType",
+		Specific:   "string",
+		Constraint: "generic.Number",
+		Pos:        token.Position{Filename: "generic.go", Line: 5, Column: 6},
+	}
+	assert.Equal(t, diagnostic{
+		Severity: "error",
+		File:     "generic.go",
+		Line:     5,
+		Column:   6,
+		Generic:  "NumberType",
+		Specific: "string",
+		Message:  err.Error(),
+		Category: "constraint-failed",
+		Code:     exitcodeConstraintFailed,
+	}, newDiagnostic(exitcodeConstraintFailed, err))
+
+	assert.Equal(t, diagnostic{
+		Severity: "error",
+		File:     "gen.go",
+		Message:  "gen.go is out of date",
+		Category: "stale",
+		Code:     exitcodeStale,
+	}, newDiagnostic(exitcodeStale, &staleError{FileName: "gen.go"}))
+
+	assert.Equal(t, diagnostic{
+		Severity: "error",
+		Message:  "bad",
+		Category: "invalid-args",
+		Code:     exitcodeInvalidArgs,
+	}, newDiagnostic(exitcodeInvalidArgs, errors.New("bad")))
+}
+
+func TestReport(t *testing.T) {
+	var buf bytes.Buffer
+	report(&buf, false, exitcodeGenFailed, errors.New("bad"))
+	assert.Equal(t, "error: bad\n", buf.String())
+
+	buf.Reset()
+	report(&buf, true, exitcodeGenFailed, errors.New("bad"))
+	var d diagnostic
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), &d)) {
+		assert.Equal(t, "gen-failed", d.Category)
+		assert.Equal(t, "bad", d.Message)
+	}
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+}
+
+func TestValidationFailed(t *testing.T) {
+	assert.Equal(t, exitcodeCheckFailed, validationFailed(&parse.TypeCheckError{}))
+	assert.Equal(t, exitcodeConstraintFailed, validationFailed(&parse.ConstraintError{}))
+	assert.Equal(t, exitcodeSourceFileInvalid, validationFailed(&parse.SourceError{}))
+	assert.Equal(t, exitcodeSourceFileInvalid, validationFailed(&os.PathError{Op: "open", Path: "gen.go", Err: os.ErrPermission}))
+	assert.Equal(t, exitcodeGenFailed, validationFailed(&parse.MissingSpecificTypeError{GenericType: "Key"}))
+}
+
+func TestUsageError(t *testing.T) {
+	code, err := usageError(true, errors.New("not enough arguments"))
+	assert.Equal(t, exitcodeInvalidArgs, code)
+
+	var buf bytes.Buffer
+	report(&buf, true, code, err)
+	var d diagnostic
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), &d)) {
+		assert.Equal(t, "invalid-args", d.Category)
+		assert.Equal(t, "not enough arguments", d.Message)
+	}
+}
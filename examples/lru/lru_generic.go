@@ -7,7 +7,7 @@ import (
 )
 
 // A Key may be any value that is comparable. See http://golang.org/ref/spec#Comparison_operators
-type Key generic.Type
+type Key generic.Comparable
 
 // A CachedValue of the cache
 type CachedValue generic.Type
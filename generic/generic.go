@@ -11,3 +11,29 @@ type Type interface{}
 // references to the specific types.
 //      var GenericType generic.Number
 type Number float64
+
+// Comparable is the placeholder type that indicates a generic value which
+// supports the == and != operators and may be used as a map key.
+// When genny is executed, variables of this type will be replaced with
+// references to the specific types.
+//      var GenericKey generic.Comparable
+type Comparable interface{}
+
+// Ordered is the placeholder type that indicates a generic value which
+// supports the ordering operators <, <=, > and >=, such as numbers and
+// strings. When genny is executed, variables of this type will be replaced
+// with references to the specific types.
+//      var GenericKey generic.Ordered
+type Ordered float64
+
+// Integer is the placeholder type that indicates a generic integer value.
+// When genny is executed, variables of this type will be replaced with
+// references to the specific types.
+//      var GenericType generic.Integer
+type Integer int
+
+// Float is the placeholder type that indicates a generic floating point value.
+// When genny is executed, variables of this type will be replaced with
+// references to the specific types.
+//      var GenericType generic.Float
+type Float float64
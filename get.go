@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kelindar/genny/library"
+	"github.com/kelindar/genny/registry"
+)
+
+// defaultURL is the template library fetched by get, unless another one is
+// specified with -url or $GENNY_URL.
+const defaultURL = "https://github.com/metabition/gennylib/raw/{version}/"
+
+// templateSource gets the sources get looks templates up in, in order:
+// "file://" URLs, the local directories (from -registry and $GENNY_PATH),
+// the library bundled with genny, the Go module cache and finally the HTTP
+// server at baseURL, whose templates are cached by version.
+func templateSource(dirs []string, baseURL string) registry.Chain {
+	chain := registry.Chain{registry.File{}}
+	for _, dir := range append(dirs, filepath.SplitList(os.Getenv("GENNY_PATH"))...) {
+		chain = append(chain, registry.Dir(dir))
+	}
+	chain = append(chain, library.Source{}, registry.Module{})
+
+	if baseURL == "" {
+		baseURL = os.Getenv("GENNY_URL")
+	}
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	if baseURL == "off" {
+		return chain
+	}
+
+	var source registry.Source = registry.HTTP{BaseURL: baseURL, DefaultVersion: "master"}
+	if dir := cacheDir(); dir != "" {
+		source = registry.Cached{Source: source, Dir: filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256([]byte(baseURL)))[:16])}
+	}
+	return append(chain, source)
+}
+
+// cacheDir gets the directory the downloaded templates are cached in, which
+// is $GENNY_CACHE or the genny directory of the user's cache.
+func cacheDir() string {
+	if dir := os.Getenv("GENNY_CACHE"); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "genny")
+	}
+	return ""
+}
+
+// readTemplate reads the template file, which is either a file relative to
+// dir or a file of a Go module, such as
+// github.com/me/templates/queue/queue.go@v1.2.0. Modules are resolved from the
+// module cache, at the version required by the go.mod of dir if the reference
+// has none.
+func readTemplate(in, dir string) (*registry.Template, error) {
+	path := filepath.FromSlash(in)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		return &registry.Template{Name: path, Location: path, Source: b}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	requires, modErr := registry.FindRequirements(dir)
+	if modErr != nil {
+		return nil, modErr
+	}
+	template, modErr := registry.Module{Requires: requires}.Fetch(registry.SplitVersion(filepath.ToSlash(in)))
+	if _, ok := modErr.(*registry.NotFoundError); ok {
+		return nil, err
+	}
+	return template, modErr
+}
+
+// list prints the templates of the library bundled with genny.
+func list(w io.Writer) (int, error) {
+	entries, err := library.List()
+	if err != nil {
+		return exitcodeInternalError, err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Name, strings.Join(entry.Generics, ", "), entry.Synopsis)
+	}
+	return 0, tw.Flush()
+}
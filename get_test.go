@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleTemplate(t *testing.T) {
+	cache := writeFiles(t, map[string]string{
+		"github.com/!me/templates@v1.2.0/queue/queue.go": queueTemplate,
+	})
+	defer os.RemoveAll(cache)
+	defer os.Setenv("GOMODCACHE", os.Getenv("GOMODCACHE"))
+	os.Setenv("GOMODCACHE", cache)
+
+	dir := writeFiles(t, map[string]string{
+		"go.mod": "module example.com/app\n\nrequire github.com/Me/templates v1.2.0\n",
+		"genny.yaml": `
+targets:
+  - in: github.com/Me/templates/queue/queue.go
+    out: queue/int_queue.go
+    source: true
+    types: "Something=int"
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	code, err := build(filepath.Join(dir, "genny.yaml"), options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	out := filepath.Join(dir, "queue", "int_queue.go")
+	b, err := ioutil.ReadFile(out)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "// Template: github.com/Me/templates/queue/queue.go@v1.2.0\n")
+		assert.Contains(t, string(b), "// genny:source -in=\"github.com/Me/templates/queue/queue.go@v1.2.0\"\n")
+		assert.Contains(t, string(b), "type IntQueue struct")
+	}
+
+	// the recorded version is used even if go.mod moves on
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\nrequire github.com/Me/templates v1.3.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, err = regen([]string{out}, options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	template, err := readTemplate("github.com/Me/templates/queue/queue.go", dir)
+	assert.Nil(t, template)
+	assert.True(t, os.IsNotExist(err), "%v", err)
+}
+
+func TestGetTests(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+
+	// the test file of a bundled template is fetched like the template
+	templates := templateSource(nil, "off")
+	template, err := templates.Fetch("lru", "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the tests must compile for any specific types, so they are type-checked
+	typeSets := []map[string]string{{"Key": "string", "Value": "int"}, {"Key": "float64", "Value": "[]string"}}
+	out := filepath.Join(dir, "lru", "gen.go")
+	code, err := generate(template.Name, bytes.NewReader(template.Source), typeSets, options{out: out, tests: true, check: true, templates: templates})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "lru", "gen_test.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "func TestStringIntLRU(t *testing.T)")
+		assert.Contains(t, string(b), "func TestFloat64SliceStringLRU(t *testing.T)")
+	}
+}
@@ -5,4 +5,5 @@ go 1.16
 require (
 	github.com/stretchr/testify v1.3.0
 	golang.org/x/tools v0.0.0-20190319232107-3f1ed9edd1b4
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,112 @@
+// Package library is the standard library of templates bundled with genny,
+// which genny get generates without fetching anything, e.g.
+//
+//	genny get set "Item=string"
+package library
+
+import (
+	"embed"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kelindar/genny/registry"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Entry describes a template of the library.
+type Entry struct {
+	// Name is the name the template is fetched by, such as "queue".
+	Name string
+
+	// Synopsis is the first sentence of the package documentation.
+	Synopsis string
+
+	// Generics are the generic types of the template, such as
+	// "Item generic.Type".
+	Generics []string
+}
+
+// List gets the templates of the library, sorted by name.
+func List() ([]Entry, error) {
+	dirs, err := fs.ReadDir(templates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, dir := range dirs {
+		name := dir.Name()
+		source, err := templates.ReadFile(templatePath(name))
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), name+".go", source, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Name:     name,
+			Synopsis: doc.Synopsis(file.Doc.Text()),
+			Generics: generics(file),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Source serves the templates of the library by their name, such as "set",
+// or by their path, such as "set/set.go". The templates are not versioned,
+// so templates requested with a version are not found.
+type Source struct{}
+
+// Fetch gets the template from the library.
+func (Source) Fetch(name, version string) (*registry.Template, error) {
+	file := name
+	if !strings.Contains(name, "/") {
+		file = path.Join(name, name+".go")
+	}
+	if version != "" || !fs.ValidPath(file) {
+		return nil, &registry.NotFoundError{Name: name, Version: version}
+	}
+
+	source, err := templates.ReadFile(path.Join("templates", file))
+	if err != nil {
+		return nil, &registry.NotFoundError{Name: name, Version: version}
+	}
+	return &registry.Template{Name: file, Location: "library:" + file, Source: source}, nil
+}
+
+// templatePath gets the path of the template with the name.
+func templatePath(name string) string {
+	return path.Join("templates", name, name+".go")
+}
+
+// generics gets the declarations of the generic types of the template.
+func generics(file *ast.File) []string {
+	var generics []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if sel, ok := ts.Type.(*ast.SelectorExpr); ok {
+				if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "generic" {
+					generics = append(generics, ts.Name.Name+" generic."+sel.Sel.Name)
+				}
+			}
+		}
+	}
+	return generics
+}
@@ -0,0 +1,73 @@
+package library_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kelindar/genny/library"
+	"github.com/kelindar/genny/parse"
+	"github.com/kelindar/genny/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+var typeSets = map[string]string{
+	"btree":         "key=int value=string",
+	"concurrentmap": "Key=string,int Value=int,bool",
+	"heap":          "Item=int,string",
+	"lru":           "Key=string,int Value=int,bool",
+	"orderedmap":    "Key=string,int Value=int,bool",
+	"queue":         "Item=int,string",
+	"ringbuffer":    "Item=int,string",
+	"set":           "Item=int,string",
+}
+
+func TestList(t *testing.T) {
+	entries, err := library.List()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+		assert.NotEmpty(t, entry.Synopsis, entry.Name)
+		assert.NotEmpty(t, entry.Generics, entry.Name)
+	}
+	assert.Equal(t, []string{"btree", "concurrentmap", "heap", "lru", "orderedmap", "queue", "ringbuffer", "set"}, names)
+	assert.Equal(t, []string{"Item generic.Comparable"}, entries[7].Generics)
+}
+
+func TestTemplates(t *testing.T) {
+	for name, types := range typeSets {
+		template, err := library.Source{}.Fetch(name, "")
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		typeSets, err := parse.TypeSet(types)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+
+		// every engine must generate code which compiles
+		for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+			g := parse.NewGenerator(parse.Options{Engine: engine, TypeCheck: true})
+			err := g.Validate(template.Name, bytes.NewReader(template.Source), typeSets, "")
+			assert.NoError(t, err, "%s (%v)", name, engine)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	template, err := library.Source{}.Fetch("set/set.go", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "set/set.go", template.Name)
+		assert.Contains(t, string(template.Source), "type ItemSet map[Item]struct{}")
+	}
+
+	for _, name := range []string{"missing", "set/missing.go", "../library.go"} {
+		_, err = library.Source{}.Fetch(name, "")
+		assert.IsType(t, &registry.NotFoundError{}, err, name)
+	}
+	_, err = library.Source{}.Fetch("set", "v1.0.0")
+	assert.IsType(t, &registry.NotFoundError{}, err)
+}
@@ -0,0 +1,81 @@
+// Package concurrentmap is a map from Keys to Values which is safe for
+// concurrent access.
+package concurrentmap
+
+import (
+	"sync"
+
+	"github.com/kelindar/genny/generic"
+)
+
+// Key is the type of the keys of the map.
+type Key generic.Comparable
+
+// Value is the type of the values of the map.
+type Value generic.Type
+
+// KeyValueConcurrentMap is a map from Key to Value types guarded by a
+// read-write lock, so that it can be used from several goroutines.
+type KeyValueConcurrentMap struct {
+	lock  sync.RWMutex
+	items map[Key]Value
+}
+
+// NewKeyValueConcurrentMap makes a new empty map.
+func NewKeyValueConcurrentMap() *KeyValueConcurrentMap {
+	return &KeyValueConcurrentMap{items: make(map[Key]Value)}
+}
+
+// Get gets the value of the key.
+func (m *KeyValueConcurrentMap) Get(k Key) (v Value, ok bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	v, ok = m.items[k]
+	return v, ok
+}
+
+// Set sets the value of the key.
+func (m *KeyValueConcurrentMap) Set(k Key, v Value) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.items[k] = v
+}
+
+// GetOrSet gets the value of the key, setting it to v first if the key is
+// not in the map. It returns whether the value was already there.
+func (m *KeyValueConcurrentMap) GetOrSet(k Key, v Value) (actual Value, loaded bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if actual, loaded = m.items[k]; loaded {
+		return actual, true
+	}
+	m.items[k] = v
+	return v, false
+}
+
+// Delete removes the key from the map.
+func (m *KeyValueConcurrentMap) Delete(k Key) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.items, k)
+}
+
+// Len gets the number of entries in the map.
+func (m *KeyValueConcurrentMap) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.items)
+}
+
+// Range calls fn for every entry of the map, in no particular order, until it
+// returns false. The map is locked for reading meanwhile, so fn must not
+// modify it.
+func (m *KeyValueConcurrentMap) Range(fn func(k Key, v Value) bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for k, v := range m.items {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
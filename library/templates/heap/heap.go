@@ -0,0 +1,82 @@
+// Package heap is a binary min-heap of Items, which pops the smallest item
+// first.
+package heap
+
+import "github.com/kelindar/genny/generic"
+
+// Item is the type of the items in the heap.
+type Item generic.Ordered
+
+// ItemHeap is a min-heap of Item types.
+type ItemHeap struct {
+	elems []Item
+}
+
+// NewItemHeap makes a new heap holding the items.
+func NewItemHeap(elems ...Item) *ItemHeap {
+	h := &ItemHeap{elems: append(make([]Item, 0, len(elems)), elems...)}
+	for i := len(h.elems)/2 - 1; i >= 0; i-- {
+		h.down(i)
+	}
+	return h
+}
+
+// Push adds an item to the heap.
+func (h *ItemHeap) Push(x Item) {
+	h.elems = append(h.elems, x)
+	h.up(len(h.elems) - 1)
+}
+
+// Pop removes and returns the smallest item of the heap, if any.
+func (h *ItemHeap) Pop() (x Item, ok bool) {
+	if len(h.elems) == 0 {
+		return x, false
+	}
+	last := len(h.elems) - 1
+	x = h.elems[0]
+	h.elems[0] = h.elems[last]
+	h.elems = h.elems[:last]
+	h.down(0)
+	return x, true
+}
+
+// Peek returns the smallest item of the heap without removing it.
+func (h *ItemHeap) Peek() (x Item, ok bool) {
+	if len(h.elems) == 0 {
+		return x, false
+	}
+	return h.elems[0], true
+}
+
+// Len gets the number of Item types in the heap.
+func (h *ItemHeap) Len() int {
+	return len(h.elems)
+}
+
+func (h *ItemHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !(h.elems[i] < h.elems[parent]) {
+			return
+		}
+		h.elems[i], h.elems[parent] = h.elems[parent], h.elems[i]
+		i = parent
+	}
+}
+
+func (h *ItemHeap) down(i int) {
+	for {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(h.elems) && h.elems[left] < h.elems[smallest] {
+			smallest = left
+		}
+		if right < len(h.elems) && h.elems[right] < h.elems[smallest] {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		h.elems[i], h.elems[smallest] = h.elems[smallest], h.elems[i]
+		i = smallest
+	}
+}
@@ -0,0 +1,24 @@
+package heap
+
+import "testing"
+
+func TestItemHeap(t *testing.T) {
+	var x Item
+	h := NewItemHeap(x, x)
+	h.Push(x)
+	if n := h.Len(); n != 3 {
+		t.Fatalf("expected 3 items, got %d", n)
+	}
+	if _, ok := h.Peek(); !ok {
+		t.Fatal("expected the smallest item")
+	}
+
+	for i := 0; i < 3; i++ {
+		if y, ok := h.Pop(); !ok || y != x {
+			t.Fatalf("expected the zero item, got %v", y)
+		}
+	}
+	if _, ok := h.Pop(); ok {
+		t.Fatal("expected an empty heap")
+	}
+}
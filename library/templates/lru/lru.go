@@ -0,0 +1,130 @@
+// Package lru is a least recently used cache of Values by Key. It is not safe
+// for concurrent access.
+package lru
+
+import "github.com/kelindar/genny/generic"
+
+// Key is the type of the keys of the cache.
+type Key generic.Comparable
+
+// Value is the type of the values of the cache.
+type Value generic.Type
+
+// KeyValueLRU is a least recently used cache of Value types by Key.
+type KeyValueLRU struct {
+	// MaxEntries is the maximum number of cache entries before an item is
+	// evicted. Zero means no limit.
+	MaxEntries int
+
+	// OnEvicted optionally specifies a callback function to be executed when
+	// an entry is purged from the cache.
+	OnEvicted func(k Key, v Value)
+
+	nodes map[Key]*keyValueLRUNode
+
+	// newest and oldest are the most and least recently used entries
+	newest, oldest *keyValueLRUNode
+}
+
+// keyValueLRUNode is an entry of a KeyValueLRU, linked to the entries used
+// before and after it.
+type keyValueLRUNode struct {
+	k            Key
+	v            Value
+	newer, older *keyValueLRUNode
+}
+
+// NewKeyValueLRU creates a new cache. If maxEntries is zero, the cache has no
+// limit and it's assumed that eviction is done by the caller.
+func NewKeyValueLRU(maxEntries int) *KeyValueLRU {
+	return &KeyValueLRU{
+		MaxEntries: maxEntries,
+		nodes:      make(map[Key]*keyValueLRUNode),
+	}
+}
+
+// Add adds a value to the cache.
+func (c *KeyValueLRU) Add(k Key, v Value) {
+	if c.nodes == nil {
+		c.nodes = make(map[Key]*keyValueLRUNode)
+	}
+	if n, ok := c.nodes[k]; ok {
+		n.v = v
+		c.touch(n)
+		return
+	}
+
+	n := &keyValueLRUNode{k: k, v: v}
+	c.nodes[k] = n
+	c.pushNewest(n)
+	if c.MaxEntries != 0 && len(c.nodes) > c.MaxEntries {
+		c.RemoveOldest()
+	}
+}
+
+// Get looks up a key's value from the cache.
+func (c *KeyValueLRU) Get(k Key) (v Value, ok bool) {
+	if n, hit := c.nodes[k]; hit {
+		c.touch(n)
+		return n.v, true
+	}
+	return v, false
+}
+
+// Remove removes the provided key from the cache.
+func (c *KeyValueLRU) Remove(k Key) {
+	if n, hit := c.nodes[k]; hit {
+		c.removeNode(n)
+	}
+}
+
+// RemoveOldest removes the oldest item from the cache.
+func (c *KeyValueLRU) RemoveOldest() {
+	if c.oldest != nil {
+		c.removeNode(c.oldest)
+	}
+}
+
+// Len returns the number of items in the cache.
+func (c *KeyValueLRU) Len() int {
+	return len(c.nodes)
+}
+
+// touch marks the entry as the most recently used one.
+func (c *KeyValueLRU) touch(n *keyValueLRUNode) {
+	if c.newest != n {
+		c.unlink(n)
+		c.pushNewest(n)
+	}
+}
+
+func (c *KeyValueLRU) pushNewest(n *keyValueLRUNode) {
+	n.newer, n.older = nil, c.newest
+	if c.newest != nil {
+		c.newest.newer = n
+	} else {
+		c.oldest = n
+	}
+	c.newest = n
+}
+
+func (c *KeyValueLRU) unlink(n *keyValueLRUNode) {
+	if n.newer != nil {
+		n.newer.older = n.older
+	} else {
+		c.newest = n.older
+	}
+	if n.older != nil {
+		n.older.newer = n.newer
+	} else {
+		c.oldest = n.newer
+	}
+}
+
+func (c *KeyValueLRU) removeNode(n *keyValueLRUNode) {
+	c.unlink(n)
+	delete(c.nodes, n.k)
+	if c.OnEvicted != nil {
+		c.OnEvicted(n.k, n.v)
+	}
+}
@@ -0,0 +1,33 @@
+package lru
+
+import "testing"
+
+func TestKeyValueLRU(t *testing.T) {
+	var (
+		k       Key
+		v       Value
+		evicted int
+	)
+	c := NewKeyValueLRU(1)
+	c.OnEvicted = func(Key, Value) { evicted++ }
+
+	// adding the same key again updates its entry
+	c.Add(k, v)
+	c.Add(k, v)
+	if n := c.Len(); n != 1 {
+		t.Fatalf("expected 1 entry, got %d", n)
+	}
+	if _, ok := c.Get(k); !ok {
+		t.Fatal("expected the key to be cached")
+	}
+
+	c.Remove(k)
+	if _, ok := c.Get(k); ok {
+		t.Fatal("expected the key to be removed")
+	}
+	c.Add(k, v)
+	c.RemoveOldest()
+	if n := c.Len(); n != 0 || evicted != 2 {
+		t.Fatalf("expected an empty cache after 2 evictions, got %d entries and %d evictions", n, evicted)
+	}
+}
@@ -0,0 +1,100 @@
+// Package orderedmap is a map from Keys to Values which remembers the order
+// in which the keys were inserted.
+package orderedmap
+
+import "github.com/kelindar/genny/generic"
+
+// Key is the type of the keys of the map.
+type Key generic.Comparable
+
+// Value is the type of the values of the map.
+type Value generic.Type
+
+// KeyValueOrderedMap is a map from Key to Value types, which iterates over
+// its entries in insertion order.
+type KeyValueOrderedMap struct {
+	nodes      map[Key]*keyValueNode
+	head, tail *keyValueNode
+}
+
+// keyValueNode is an entry of a KeyValueOrderedMap, linked to the entries
+// inserted before and after it.
+type keyValueNode struct {
+	k          Key
+	v          Value
+	prev, next *keyValueNode
+}
+
+// NewKeyValueOrderedMap makes a new empty ordered map.
+func NewKeyValueOrderedMap() *KeyValueOrderedMap {
+	return &KeyValueOrderedMap{nodes: make(map[Key]*keyValueNode)}
+}
+
+// Set sets the value of the key. A new key is added at the end of the map,
+// while an existing key keeps its position.
+func (m *KeyValueOrderedMap) Set(k Key, v Value) {
+	if n, ok := m.nodes[k]; ok {
+		n.v = v
+		return
+	}
+
+	n := &keyValueNode{k: k, v: v, prev: m.tail}
+	if m.tail != nil {
+		m.tail.next = n
+	} else {
+		m.head = n
+	}
+	m.tail = n
+	m.nodes[k] = n
+}
+
+// Get gets the value of the key.
+func (m *KeyValueOrderedMap) Get(k Key) (v Value, ok bool) {
+	if n, ok := m.nodes[k]; ok {
+		return n.v, true
+	}
+	return v, false
+}
+
+// Delete removes the key from the map.
+func (m *KeyValueOrderedMap) Delete(k Key) {
+	n, ok := m.nodes[k]
+	if !ok {
+		return
+	}
+
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		m.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		m.tail = n.prev
+	}
+	delete(m.nodes, k)
+}
+
+// Len gets the number of entries in the map.
+func (m *KeyValueOrderedMap) Len() int {
+	return len(m.nodes)
+}
+
+// Order gets the keys of the map in insertion order.
+func (m *KeyValueOrderedMap) Order() []Key {
+	order := make([]Key, 0, len(m.nodes))
+	for n := m.head; n != nil; n = n.next {
+		order = append(order, n.k)
+	}
+	return order
+}
+
+// Range calls fn for every entry in insertion order, until it returns false.
+func (m *KeyValueOrderedMap) Range(fn func(k Key, v Value) bool) {
+	for n := m.head; n != nil; n = n.next {
+		if !fn(n.k, n.v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package orderedmap
+
+import "testing"
+
+func TestKeyValueOrderedMap(t *testing.T) {
+	var (
+		k Key
+		v Value
+	)
+	m := NewKeyValueOrderedMap()
+	m.Set(k, v)
+	m.Set(k, v)
+	if order := m.Order(); len(order) != 1 || order[0] != k {
+		t.Fatalf("expected the key once, got %v", order)
+	}
+	if _, ok := m.Get(k); !ok {
+		t.Fatal("expected the key to be set")
+	}
+
+	entries := 0
+	m.Range(func(Key, Value) bool {
+		entries++
+		return true
+	})
+	if entries != 1 {
+		t.Fatalf("expected 1 entry, got %d", entries)
+	}
+
+	m.Delete(k)
+	if n := m.Len(); n != 0 {
+		t.Fatalf("expected an empty map, got %d entries", n)
+	}
+}
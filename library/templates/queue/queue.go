@@ -0,0 +1,45 @@
+// Package queue is a first-in first-out queue of Items.
+package queue
+
+import "github.com/kelindar/genny/generic"
+
+// Item is the type of the items in the queue.
+type Item generic.Type
+
+// ItemQueue represents a queue of Item types.
+type ItemQueue struct {
+	elems []Item
+}
+
+// NewItemQueue makes a new queue of Item types, holding the items.
+func NewItemQueue(elems ...Item) *ItemQueue {
+	return &ItemQueue{elems: append(make([]Item, 0, len(elems)), elems...)}
+}
+
+// Push adds an item to the back of the queue.
+func (q *ItemQueue) Push(x Item) {
+	q.elems = append(q.elems, x)
+}
+
+// Pop removes and returns the item at the front of the queue, if any.
+func (q *ItemQueue) Pop() (x Item, ok bool) {
+	if len(q.elems) == 0 {
+		return x, false
+	}
+	x = q.elems[0]
+	q.elems = q.elems[1:]
+	return x, true
+}
+
+// Peek returns the item at the front of the queue without removing it.
+func (q *ItemQueue) Peek() (x Item, ok bool) {
+	if len(q.elems) == 0 {
+		return x, false
+	}
+	return q.elems[0], true
+}
+
+// Len gets the current number of Item types in the queue.
+func (q *ItemQueue) Len() int {
+	return len(q.elems)
+}
@@ -0,0 +1,67 @@
+// Package ringbuffer is a fixed size first-in first-out buffer of Items, which
+// overwrites the oldest items when it is full.
+package ringbuffer
+
+import "github.com/kelindar/genny/generic"
+
+// Item is the type of the items in the buffer.
+type Item generic.Type
+
+// ItemRingBuffer is a ring buffer of Item types.
+type ItemRingBuffer struct {
+	elems []Item
+	head  int
+	size  int
+}
+
+// NewItemRingBuffer makes a new empty buffer, which holds up to capacity items.
+func NewItemRingBuffer(capacity int) *ItemRingBuffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ItemRingBuffer{elems: make([]Item, capacity)}
+}
+
+// Push adds an item to the back of the buffer. If the buffer is full, the
+// oldest item is overwritten and returned.
+func (b *ItemRingBuffer) Push(x Item) (overwritten Item, ok bool) {
+	tail := (b.head + b.size) % len(b.elems)
+	if b.size == len(b.elems) {
+		overwritten, ok = b.elems[tail], true
+		b.head = (b.head + 1) % len(b.elems)
+	} else {
+		b.size++
+	}
+	b.elems[tail] = x
+	return overwritten, ok
+}
+
+// Pop removes and returns the oldest item of the buffer, if any.
+func (b *ItemRingBuffer) Pop() (x Item, ok bool) {
+	if b.size == 0 {
+		return x, false
+	}
+	var zero Item
+	x, b.elems[b.head] = b.elems[b.head], zero
+	b.head = (b.head + 1) % len(b.elems)
+	b.size--
+	return x, true
+}
+
+// Peek returns the oldest item of the buffer without removing it.
+func (b *ItemRingBuffer) Peek() (x Item, ok bool) {
+	if b.size == 0 {
+		return x, false
+	}
+	return b.elems[b.head], true
+}
+
+// Len gets the number of Item types in the buffer.
+func (b *ItemRingBuffer) Len() int {
+	return b.size
+}
+
+// Cap gets the maximum number of Item types in the buffer.
+func (b *ItemRingBuffer) Cap() int {
+	return len(b.elems)
+}
@@ -0,0 +1,30 @@
+package ringbuffer
+
+import "testing"
+
+func TestItemRingBuffer(t *testing.T) {
+	var x Item
+	b := NewItemRingBuffer(2)
+	for i := 0; i < 2; i++ {
+		if _, overwritten := b.Push(x); overwritten {
+			t.Fatalf("expected push %d to fit", i)
+		}
+	}
+
+	// the buffer is full, so the oldest item is overwritten
+	if _, overwritten := b.Push(x); !overwritten {
+		t.Fatal("expected the oldest item to be overwritten")
+	}
+	if n, c := b.Len(), b.Cap(); n != 2 || c != 2 {
+		t.Fatalf("expected 2 items out of 2, got %d out of %d", n, c)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := b.Pop(); !ok {
+			t.Fatalf("expected pop %d to return an item", i)
+		}
+	}
+	if _, ok := b.Peek(); ok {
+		t.Fatal("expected an empty buffer")
+	}
+}
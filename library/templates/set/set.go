@@ -0,0 +1,83 @@
+// Package set is an unordered set of Items.
+package set
+
+import "github.com/kelindar/genny/generic"
+
+// Item is the type of the items in the set.
+type Item generic.Comparable
+
+// ItemSet represents a set of Item types.
+type ItemSet map[Item]struct{}
+
+// NewItemSet makes a new set of Item types, holding the items.
+func NewItemSet(elems ...Item) ItemSet {
+	s := make(ItemSet, len(elems))
+	for _, x := range elems {
+		s[x] = struct{}{}
+	}
+	return s
+}
+
+// Add adds the item to the set.
+func (s ItemSet) Add(x Item) {
+	s[x] = struct{}{}
+}
+
+// Remove removes the item from the set.
+func (s ItemSet) Remove(x Item) {
+	delete(s, x)
+}
+
+// Contains returns whether the item is in the set.
+func (s ItemSet) Contains(x Item) bool {
+	_, ok := s[x]
+	return ok
+}
+
+// Len gets the number of Item types in the set.
+func (s ItemSet) Len() int {
+	return len(s)
+}
+
+// Items gets the items of the set, in no particular order.
+func (s ItemSet) Items() []Item {
+	elems := make([]Item, 0, len(s))
+	for x := range s {
+		elems = append(elems, x)
+	}
+	return elems
+}
+
+// Union makes a new set with the items which are in either set.
+func (s ItemSet) Union(other ItemSet) ItemSet {
+	union := make(ItemSet, len(s)+len(other))
+	for x := range s {
+		union[x] = struct{}{}
+	}
+	for x := range other {
+		union[x] = struct{}{}
+	}
+	return union
+}
+
+// Intersect makes a new set with the items which are in both sets.
+func (s ItemSet) Intersect(other ItemSet) ItemSet {
+	intersection := make(ItemSet)
+	for x := range s {
+		if _, ok := other[x]; ok {
+			intersection[x] = struct{}{}
+		}
+	}
+	return intersection
+}
+
+// Difference makes a new set with the items which are not in the other set.
+func (s ItemSet) Difference(other ItemSet) ItemSet {
+	difference := make(ItemSet)
+	for x := range s {
+		if _, ok := other[x]; !ok {
+			difference[x] = struct{}{}
+		}
+	}
+	return difference
+}
@@ -2,19 +2,21 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
+	"path/filepath"
 
-	// "path"
 	"runtime/debug"
+	"sort"
 	"strings"
 
 	"github.com/kelindar/genny/out"
 	"github.com/kelindar/genny/parse"
+	"github.com/kelindar/genny/registry"
 )
 
 /*
@@ -33,134 +35,558 @@ const (
 	exitcodeSourceFileInvalid
 	exitcodeDestFileFailed
 	exitcodeInternalError
+	exitcodeCheckFailed
+	exitcodeConstraintFailed
+	exitcodeBuildFailed
+	exitcodeStale
 )
 
 func main() {
 	var (
 		mainErr  error
 		exitCode int
+		asJSON   = flag.Bool("json", false, "write errors to stderr as JSON lines")
 	)
 
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Fprintf(os.Stderr, "panic: %v: %s\n", r, debug.Stack())
-			exitCode = exitcodeInternalError
+			if !*asJSON {
+				fmt.Fprintf(os.Stderr, "panic: %v: %s\n", r, debug.Stack())
+			}
+			exitCode, mainErr = exitcodeInternalError, fmt.Errorf("panic: %v", r)
 		}
 		if mainErr != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", mainErr)
+			report(os.Stderr, *asJSON, exitCode, mainErr)
 		}
 		os.Exit(exitCode)
 	}()
 
 	var (
-		in      = flag.String("in", "", "file to parse instead of stdin")
-		out     = flag.String("out", "", "file to save output to instead of stdout")
+		in      = flag.String("in", "", "file or package directory to parse instead of stdin")
+		out     = flag.String("out", "", "file (or directory, for a package) to save output to instead of stdout")
 		pkgName = flag.String("pkg", "", "package name for generated files")
 		genTag  = flag.String("tag", "", "build tag that is stripped from output")
+		header  = flag.String("header", "", "template of the header comment of generated files, which must contain a \"Code generated ... DO NOT EDIT.\" line")
 		useAst  = flag.Bool("ast", false, "whether to use AST implementation")
+		engineN = flag.String("engine", "", "implementation to use: legacy, ast or types (overrides -ast)")
+		check   = flag.Bool("check", false, "type-check every instantiation in the output package before writing")
+		tests   = flag.Bool("tests", false, "also generate the _test.go files of the template")
+		origin  = flag.Bool("source", false, "record the arguments in a genny:source block of the output, for genny regen")
+		split   = flag.Bool("split", false, "write the code of every type set to its own file, named after -out and the specific types")
+		config  = flag.String("config", "", "config file for build (default genny.yaml, genny.yml or genny.json)")
+		baseURL = flag.String("url", "", "base URL of the templates fetched by get, or \"off\" (default $GENNY_URL or the gennylib repository)")
 		imports Strings
-		prefix  = "https://github.com/metabition/gennylib/raw/master/"
+		dirs    Strings
+		groups  Strings
 	)
 	flag.Var(&imports, "imp", "specify an import explicitly (can be specified multiple times)")
-	flag.Usage = usage
-	flag.Parse()
+	flag.Var(&groups, "group", "named group of specific types usable in {types}, such as KEYS=int,string (can be specified multiple times)")
+	flag.Var(&dirs, "registry", "local directory of templates for get, searched before the network (can be specified multiple times)")
+	// flag errors are reported like the other errors, so that -json applies
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.CommandLine.SetOutput(ioutil.Discard)
+	err := flag.CommandLine.Parse(os.Args[1:])
+	flag.CommandLine.SetOutput(os.Stderr)
+	if err == flag.ErrHelp {
+		usage()
+		return
+	}
+	if err != nil {
+		exitCode, mainErr = usageError(*asJSON, err)
+		return
+	}
 	args := flag.Args()
 
+	engine := parse.EngineLegacy
+	if *useAst {
+		engine = parse.EngineAst
+	}
+	if *engineN != "" {
+		if engine, err = parse.ParseEngine(*engineN); err != nil {
+			exitCode, mainErr = exitcodeInvalidArgs, err
+			return
+		}
+	}
+
+	if len(args) == 1 && strings.ToLower(args[0]) == "build" {
+		exitCode, mainErr = build(*config, options{header: *header, engine: engine, check: *check, tests: *tests, source: *origin, groups: groups, json: *asJSON})
+		return
+	}
+	if len(args) == 1 && strings.ToLower(args[0]) == "list" {
+		exitCode, mainErr = list(os.Stdout)
+		return
+	}
+	if len(args) == 1 && strings.ToLower(args[0]) == "verify" {
+		exitCode, mainErr = build(*config, options{header: *header, engine: engine, check: *check, tests: *tests, source: *origin, groups: groups, verify: true, json: *asJSON})
+		return
+	}
+
 	if len(args) < 2 {
-		usage()
-		os.Exit(exitcodeInvalidArgs)
+		exitCode, mainErr = usageError(*asJSON, errors.New("not enough arguments"))
+		return
 	}
 
-	if strings.ToLower(args[0]) != "gen" && strings.ToLower(args[0]) != "get" {
-		usage()
-		os.Exit(exitcodeInvalidArgs)
+	command := strings.ToLower(args[0])
+	if command == "regen" {
+		exitCode, mainErr = regen(args[1:], options{check: *check, json: *asJSON})
+		return
+	}
+	if command != "gen" && command != "get" && command != "verify" {
+		exitCode, mainErr = usageError(*asJSON, fmt.Errorf("unknown command %q", args[0]))
+		return
+	}
+	if command == "get" && len(args) < 3 {
+		exitCode, mainErr = usageError(*asJSON, errors.New("not enough arguments to get"))
+		return
+	}
+	if command == "verify" && *out == "" {
+		exitCode, mainErr = exitcodeInvalidArgs, errors.New("verify requires -out")
+		return
 	}
 
-	// parse the typesets
-	var setsArg = args[1]
+	// parse the typesets, which may be given as several arguments
+	var setsArgs = args[1:]
 	if strings.ToLower(args[0]) == "get" {
-		setsArg = args[2]
+		setsArgs = args[2:]
 	}
-	typeSets, err := parse.TypeSet(setsArg)
+	typeSets, err := expandTypes(groups, setsArgs, *split)
 	if err != nil {
 		exitCode, mainErr = exitcodeInvalidTypeSet, err
 		return
 	}
 
-	outWriter := newWriter(*out)
-
+	var (
+		filename  = *in
+		version   string
+		source    io.ReadSeeker
+		templates registry.Source
+	)
 	if strings.ToLower(args[0]) == "get" {
-		if len(args) != 3 {
-			fmt.Println("not enough arguments to get")
-			usage()
-			os.Exit(exitcodeInvalidArgs)
-		}
-		r, err := http.Get(prefix + args[1])
-		if err != nil {
-			exitCode, mainErr = exitcodeGetFailed, err
+		if *tests && *out == "" {
+			exitCode, mainErr = exitcodeInvalidArgs, errors.New("-tests requires -out")
 			return
 		}
-		b, err := ioutil.ReadAll(r.Body)
+		templates = templateSource(dirs, *baseURL)
+		template, err := templates.Fetch(registry.SplitVersion(args[1]))
 		if err != nil {
 			exitCode, mainErr = exitcodeGetFailed, err
 			return
 		}
-		r.Body.Close()
-		br := bytes.NewReader(b)
-		err = gen(*in, *pkgName, br, typeSets, imports, outWriter, *genTag, *useAst)
+		filename, version, source = template.Name, template.Version, bytes.NewReader(template.Source)
+	} else if info, err := os.Stat(*in); err == nil && info.IsDir() {
+		exitCode, mainErr = generatePackage(*in, typeSets, options{
+			out:     *out,
+			pkgName: *pkgName,
+			tag:     *genTag,
+			header:  *header,
+			imports: imports,
+			engine:  engine,
+			check:   *check,
+			tests:   *tests,
+			source:  *origin,
+			split:   *split,
+			groups:  groups,
+			types:   setsArgs,
+			verify:  command == "verify",
+		})
+		return
+	} else if *tests && (*in == "" || *out == "") {
+		exitCode, mainErr = exitcodeInvalidArgs, errors.New("-tests requires -in and -out")
+		return
+	} else if *origin && (*in == "" || *out == "") {
+		exitCode, mainErr = exitcodeInvalidArgs, errors.New("-source requires -in and -out")
+		return
+	} else if *split && *out == "" {
+		exitCode, mainErr = exitcodeInvalidArgs, errors.New("-split requires -out")
+		return
 	} else if len(*in) > 0 {
-		var file *os.File
-		file, err = os.Open(*in)
+		template, err := readTemplate(*in, ".")
 		if err != nil {
-			exitCode = exitcodeSourceFileInvalid
+			exitCode, mainErr = exitcodeSourceFileInvalid, err
 			return
 		}
-		defer file.Close()
-		err = gen(*in, *pkgName, file, typeSets, imports, outWriter, *genTag, *useAst)
+		filename, version, source = template.Name, template.Version, bytes.NewReader(template.Source)
 	} else {
-		var source []byte
-		source, err = ioutil.ReadAll(os.Stdin)
+		var b []byte
+		b, err = ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			exitCode = exitcodeStdinFailed
+			exitCode, mainErr = exitcodeStdinFailed, err
 			return
 		}
-		reader := bytes.NewReader(source)
-		err = gen("stdin", *pkgName, reader, typeSets, imports, outWriter, *genTag, *useAst)
+		filename, source = "stdin", bytes.NewReader(b)
+	}
+
+	exitCode, mainErr = generate(filename, source, typeSets, options{
+		out:       *out,
+		pkgName:   *pkgName,
+		tag:       *genTag,
+		header:    *header,
+		imports:   imports,
+		engine:    engine,
+		check:     *check,
+		tests:     *tests,
+		source:    *origin,
+		split:     *split,
+		groups:    groups,
+		types:     setsArgs,
+		version:   version,
+		templates: templates,
+		verify:    command == "verify",
+	})
+}
+
+// options are the settings of a single generation.
+type options struct {
+	out     string
+	pkgName string
+	tag     string
+	header  string
+	imports []string
+	engine  parse.Engine
+	check   bool
+	tests   bool
+	source  bool
+	split   bool
+	groups  []string
+	types   []string
+	version string
+	verify  bool
+	json    bool
+
+	// templates is the source the template was fetched from by get, which
+	// its test file is fetched from as well
+	templates registry.Source
+}
+
+// parseTypes gets the type sets of the type strings, which may use the groups
+// of the definitions.
+func parseTypes(definitions, args []string) ([]map[string]string, error) {
+	groups, err := parse.NewGroups(definitions...)
+	if err != nil {
+		return nil, err
+	}
+	return groups.TypeSets(args...)
+}
+
+// expandTypes gets the type sets of the type strings like parseTypes, unless
+// they are split. generateSplit expands those one at a time, so that they are
+// never all held in memory at once, and they are only checked here.
+func expandTypes(definitions, args []string, split bool) ([]map[string]string, error) {
+	if split {
+		_, err := iterateTypes(definitions, args)
+		return nil, err
+	}
+	return parseTypes(definitions, args)
+}
+
+// iterateTypes gets an iterator over the type sets of the type strings,
+// which may use the groups of the definitions.
+func iterateTypes(definitions, args []string) (*parse.TypeSetIterator, error) {
+	groups, err := parse.NewGroups(definitions...)
+	if err != nil {
+		return nil, err
+	}
+	return groups.IterateTypeSets(args...)
+}
+
+// generator creates the generator which generates the code of the template
+// (a file or a package directory) with these options.
+func (o options) generator(in string) *parse.Generator {
+	opts := parse.Options{
+		Engine:    o.engine,
+		PkgName:   o.pkgName,
+		Imports:   o.imports,
+		StripTag:  o.tag,
+		Header:    o.header,
+		TypeCheck: o.check,
+		Tests:     o.tests,
+
+		TemplateVersion: o.version,
+	}
+	if o.source {
+		opts.Origin = o.origin(in)
+	}
+	return parse.NewGenerator(opts)
+}
+
+// origin gets the arguments recorded in the generated code, with the path
+// of the template relative to the directory of the output (which is -out
+// itself for a template directory).
+func (o options) origin(in string) *parse.Origin {
+	dir := filepath.Dir(o.out)
+	if info, err := os.Stat(in); err == nil && info.IsDir() {
+		dir = o.out
+	}
+	if o.version != "" {
+		in += "@" + o.version
+	} else if rel, err := filepath.Rel(dir, in); err == nil {
+		in = filepath.ToSlash(rel)
+	}
+
+	engine := ""
+	if o.engine != parse.EngineLegacy {
+		engine = o.engine.String()
+	}
+	return &parse.Origin{
+		In:      in,
+		Groups:  o.groups,
+		Types:   o.types,
+		Pkg:     o.pkgName,
+		Imports: o.imports,
+		Tag:     o.tag,
+		Engine:  engine,
+		Header:  o.header,
+	}
+}
+
+// validationFailed gets the exit code for an error returned by the validation
+// of the type sets. Besides type-check and constraint failures, the sources
+// of the template and the output package may fail to be read or parsed, and
+// anything else fails like the generation would.
+func validationFailed(err error) int {
+	var (
+		typeCheck  *parse.TypeCheckError
+		constraint *parse.ConstraintError
+		source     *parse.SourceError
+		path       *os.PathError
+	)
+	switch {
+	case errors.As(err, &typeCheck):
+		return exitcodeCheckFailed
+	case errors.As(err, &constraint):
+		return exitcodeConstraintFailed
+	case errors.As(err, &source), errors.As(err, &path):
+		return exitcodeSourceFileInvalid
+	}
+	return exitcodeGenFailed
+}
+
+// write writes the generated code to the file (or stdout if it is empty). In
+// verify mode, the code is compared with the file instead, reporting a stale
+// file along with the differences.
+func (o options) write(fileName string, generate func(io.Writer) error) (int, error) {
+	if o.verify {
+		var buf bytes.Buffer
+		if err := generate(&buf); err != nil {
+			return exitcodeGenFailed, err
+		}
+		return verify(fileName, buf.Bytes())
+	}
+
+	// the file is only replaced once the code was generated successfully
+	outWriter := newWriter(fileName)
+	if err := generate(outWriter); err != nil {
+		if aborter, ok := outWriter.(interface{ Abort() error }); ok {
+			aborter.Abort()
+		}
+		return exitcodeGenFailed, err
+	}
+	if closer, ok := outWriter.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return exitcodeDestFileFailed, err
+		}
+	}
+	return 0, nil
+}
+
+// verify compares the generated code with the contents of the file, printing
+// a unified diff if the file is missing or out of date.
+func verify(fileName string, generated []byte) (int, error) {
+	existing, err := ioutil.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return exitcodeDestFileFailed, err
+	}
+
+	if diff := unifiedDiff(fileName, fileName+" (regenerated)", existing, generated); diff != "" {
+		fmt.Print(diff)
+		return exitcodeStale, &staleError{FileName: fileName}
+	}
+	return 0, nil
+}
+
+// generate validates the type sets against the source and writes the
+// generated code, returning the exit code and error to report.
+func generate(filename string, source io.ReadSeeker, typeSets []map[string]string, opts options) (int, error) {
+	if opts.split {
+		return generateSplit(filename, source, opts)
+	}
+
+	// make sure the specific types are acceptable before writing anything
+	g := opts.generator(filename)
+	if err := g.Validate(filename, source, typeSets, opts.out); err != nil {
+		return validationFailed(err), err
+	}
+	var tests *registry.Template
+	if opts.tests {
+		var (
+			code int
+			err  error
+		)
+		if tests, code, err = fetchTests(filename, opts); err != nil {
+			return code, err
+		}
+		if err := g.ValidateTests(filename, source, tests.Name, bytes.NewReader(tests.Source), typeSets, opts.out); err != nil {
+			return validationFailed(err), err
+		}
 	}
 
 	// do the work
+	code, err := opts.write(opts.out, func(w io.Writer) error {
+		return gen(g, filename, source, typeSets, w)
+	})
+	if err != nil && code != exitcodeStale {
+		return code, err
+	}
+
+	// a stale test file is only reported if the code itself is up to date
+	if tests != nil {
+		testCode, testErr := opts.write(testFileName(opts.out), func(w io.Writer) error {
+			output, err := opts.generator(tests.Name).GenerateTests(filename, source, tests.Name, bytes.NewReader(tests.Source), typeSets)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(output)
+			return err
+		})
+		if testErr != nil && (err == nil || testCode != exitcodeStale) {
+			return testCode, testErr
+		}
+	}
+	return code, err
+}
+
+// fetchTests fetches the test file of the template, such as queue_test.go
+// for queue.go, from the source the template was fetched from.
+func fetchTests(filename string, opts options) (*registry.Template, int, error) {
+	if opts.templates != nil {
+		template, err := opts.templates.Fetch(testFileName(filename), opts.version)
+		if err != nil {
+			return nil, exitcodeGetFailed, err
+		}
+		return template, 0, nil
+	}
+
+	in := testFileName(filename)
+	if opts.version != "" {
+		in += "@" + opts.version
+	}
+	template, err := readTemplate(in, ".")
+	if err != nil {
+		return nil, exitcodeSourceFileInvalid, err
+	}
+	return template, 0, nil
+}
+
+// testFileName gets the name of the test file which belongs to a Go file.
+func testFileName(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_test.go"
+}
+
+// generatePackage generates every file of the template package in dir into
+// the output directory, keeping the names of the template files.
+func generatePackage(dir string, typeSets []map[string]string, opts options) (int, error) {
+	if opts.split {
+		return exitcodeInvalidArgs, errors.New("-split does not support template directories")
+	}
+	if opts.out == "" {
+		return exitcodeInvalidArgs, errors.New("-out must specify a directory when -in is a directory")
+	}
+	if info, err := os.Stat(opts.out); err == nil && !info.IsDir() {
+		return exitcodeInvalidArgs, fmt.Errorf("%s is not a directory", opts.out)
+	}
+
+	// make sure the specific types are acceptable before writing anything
+	g := opts.generator(dir)
+	if err := g.ValidatePackage(dir, typeSets, opts.out); err != nil {
+		return validationFailed(err), err
+	}
+
+	outputs, err := g.GeneratePackage(dir, typeSets)
 	if err != nil {
-		exitCode, mainErr = exitcodeGenFailed, err
+		return exitcodeGenFailed, err
+	}
+	if !opts.verify {
+		if err := os.MkdirAll(opts.out, 0755); err != nil {
+			return exitcodeDestFileFailed, err
+		}
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// keep going after a stale file, so that every difference is reported
+	var stale error
+	for _, name := range names {
+		output := outputs[name]
+		code, err := opts.write(filepath.Join(opts.out, name), func(w io.Writer) error {
+			_, err := w.Write(output)
+			return err
+		})
+		switch {
+		case code == exitcodeStale:
+			stale = err
+		case err != nil:
+			return code, err
+		}
 	}
+	if stale != nil {
+		return exitcodeStale, stale
+	}
+	return 0, nil
 }
 
 func usage() {
-	fmt.Fprintln(os.Stderr, `usage: genny [{flags}] gen "{types}"
+	fmt.Fprintln(os.Stderr, `usage: genny [{flags}] gen "{types}"...
+       genny [{flags}] get <package/file> "{types}"...
+       genny [{flags}] verify "{types}"...
+       genny [-check] regen <file>...
+       genny list
+       genny [-config={file}] build
+       genny [-config={file}] verify
 
 gen - generates type specific code from generic code.
-get <package/file> - fetch a generic template from the online library and gen it.
+get <package/file> - fetch a generic template (see -registry and -url) and gen it.
+build - generates every target listed in the config file.
+verify - checks that -out (or every target of the config file) is up to date, printing a diff if not.
+regen <file>... - regenerates files generated with -source in place, using the recorded arguments.
+list - lists the templates bundled with genny, which get generates by name (e.g. genny get set "Item=string").
 
 {flags}  - (optional) Command line flags (see below)
-{types}  - (required) Specific types for each generic type in the source
+{types}  - (required) Specific types for each generic type in the source, generating every combination;
+           several {types} generate the combinations of each of them
 {types} format:  {generic}={specific}[,another][ {generic2}={specific2}]
+                 {generic},{generic2}={specific}:{specific2}[;{another}:{another2}]
 
 Examples:
   Generic=Specific
   Generic1=Specific1 Generic2=Specific2
   Generic1=Specific1,Specific2 Generic2=Specific3,Specific4
   Generic=SpecificTitle:package.Type,AnotherSpecific
+  Generic=map[string]int,func(a, b int) bool,'chan<- int'
+  Generic1,Generic2=Specific1:Specific3;Specific2:Specific4
+  Generic1=BUILTINS-error,uintptr Generic2=BUILTINS&NUMBERS Generic1!=Generic2
 
 Flags:`)
 	flag.PrintDefaults()
 }
 
+// usageError prints the usage for an invalid command line, unless errors are
+// written as JSON, and returns the error to report.
+func usageError(asJSON bool, err error) (int, error) {
+	if !asJSON {
+		usage()
+	}
+	return exitcodeInvalidArgs, err
+}
+
 func newWriter(fileName string) io.Writer {
 	if fileName == "" {
 		return os.Stdout
 	}
-	lf := &out.LazyFile{FileName: fileName}
-	defer lf.Close()
-	return lf
+	return &out.ChangedFile{FileName: fileName}
 }
 
 func fatal(code int, a ...interface{}) {
@@ -168,19 +594,104 @@ func fatal(code int, a ...interface{}) {
 	os.Exit(code)
 }
 
+// generateSplit generates the code of every type set into its own file,
+// named after -out and the specific types (such as gen-queue_int.go for
+// -out=gen-queue.go), writing every file before generating the next one.
+func generateSplit(filename string, source io.ReadSeeker, opts options) (int, error) {
+	if opts.tests {
+		return exitcodeInvalidArgs, errors.New("-split does not support -tests")
+	}
+	typeSets, err := iterateTypes(opts.groups, opts.types)
+	if err != nil {
+		return exitcodeInvalidTypeSet, err
+	}
+
+	// make sure the specific types are acceptable, and that every type set
+	// gets its own file, before writing anything
+	g := opts.generator(filename)
+	var duplicate map[string]string
+	fileNames := make(map[string]struct{})
+	err = g.ValidateEach(filename, source, typeSets, func(typeSet map[string]string) (string, error) {
+		fileName := splitFileName(opts.out, typeSet)
+		if _, ok := fileNames[fileName]; ok {
+			duplicate = typeSet
+			return "", errors.New("duplicate file")
+		}
+		fileNames[fileName] = struct{}{}
+		return fileName, nil
+	})
+	if duplicate != nil {
+		return exitcodeInvalidTypeSet, splitConflict(typeSets, duplicate, opts.out)
+	}
+	if err != nil {
+		return validationFailed(err), err
+	}
+	typeSets.Reset()
+
+	// keep going after a stale file, so that every difference is reported
+	var (
+		writeCode int
+		stale     error
+	)
+	err = g.GenerateEach(filename, source, typeSets, func(typeSet map[string]string, output []byte) error {
+		code, err := opts.write(splitFileName(opts.out, typeSet), func(w io.Writer) error {
+			_, err := w.Write(output)
+			return err
+		})
+		switch {
+		case code == exitcodeStale:
+			stale = err
+		case err != nil:
+			writeCode = code
+			return err
+		}
+		return nil
+	})
+	switch {
+	case err != nil && writeCode != 0:
+		return writeCode, err
+	case err != nil:
+		return exitcodeGenFailed, err
+	case stale != nil:
+		return exitcodeStale, stale
+	}
+	return 0, nil
+}
+
+// splitConflict gets the error for a type set which would be written to the
+// same file as an earlier one with -split. Only the file names are kept while
+// validating, so the earlier type set is found by iterating again.
+func splitConflict(typeSets *parse.TypeSetIterator, duplicate map[string]string, out string) error {
+	fileName := splitFileName(out, duplicate)
+	typeSets.Reset()
+	for typeSets.Next() {
+		if typeSet := typeSets.TypeSet(); splitFileName(out, typeSet) == fileName {
+			return fmt.Errorf("the type sets %q and %q would both be written to %s", parse.FormatTypeSet(typeSet), parse.FormatTypeSet(duplicate), fileName)
+		}
+	}
+	return fmt.Errorf("the type set %q would be written to %s twice", parse.FormatTypeSet(duplicate), fileName)
+}
+
+// splitFileName gets the name of the file generated for the type set with
+// -split, which is the -out file suffixed with the specific types.
+func splitFileName(out string, typeSet map[string]string) string {
+	ext := filepath.Ext(out)
+	return strings.TrimSuffix(out, ext) + "_" + parse.FileSuffix(typeSet) + ext
+}
+
 // gen performs the generic generation.
-func gen(filename, pkgName string, in io.ReadSeeker, typesets []map[string]string, imports []string, out io.Writer, tag string, useAst bool) error {
+func gen(g *parse.Generator, filename string, in io.ReadSeeker, typesets []map[string]string, out io.Writer) error {
 
 	var output []byte
 	var err error
 
-	output, err = parse.Generics(filename, pkgName, in, typesets, imports, tag, useAst)
+	output, err = g.Generate(filename, in, typesets)
 	if err != nil {
 		return err
 	}
 
-	out.Write(output)
-	return nil
+	_, err = out.Write(output)
+	return err
 }
 
 // Strings is a list of strings for flag
@@ -0,0 +1,93 @@
+package out
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// AtomicFile is an io.WriteCloser which writes to a temporary file next to the file it is
+// supposed to write in, and only renames it to the file on Close. An interrupted or aborted
+// write therefore never leaves a truncated or half-written file behind. Like LazyFile, nothing
+// is created if no write happens.
+type AtomicFile struct {
+	// FileName is path to the file to which genny will write.
+	FileName string
+
+	// Mode is the permission of the file, which defaults to those of the file being replaced
+	// or 0644 for a new file.
+	Mode os.FileMode
+
+	file *os.File
+	err  error
+}
+
+// Write writes to the temporary file and creates it the first time it is called.
+func (af *AtomicFile) Write(p []byte) (int, error) {
+	if af.err != nil {
+		return 0, af.err
+	}
+	if af.file == nil {
+		dir := path.Dir(af.FileName)
+		if af.err = os.MkdirAll(dir, 0755); af.err != nil {
+			return 0, af.err
+		}
+		if af.file, af.err = ioutil.TempFile(dir, "."+path.Base(af.FileName)+".tmp"); af.err != nil {
+			return 0, af.err
+		}
+	}
+
+	n, err := af.file.Write(p)
+	if err != nil {
+		af.err = err
+	}
+	return n, err
+}
+
+// Close replaces the file with the temporary file. If any write failed, the temporary file is
+// discarded and the error of the write is returned. Returns nil if no file is created.
+func (af *AtomicFile) Close() error {
+	if af.file == nil {
+		return af.err
+	}
+	if af.err != nil {
+		af.Abort()
+		return af.err
+	}
+
+	tmp := af.file
+	af.file = nil
+	err := tmp.Close()
+	if err == nil {
+		err = os.Chmod(tmp.Name(), af.mode())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), af.FileName)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Abort discards everything written so far, leaving the file untouched.
+func (af *AtomicFile) Abort() error {
+	if af.file == nil {
+		return nil
+	}
+	tmp := af.file
+	af.file = nil
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// mode gets the permission of the file being written.
+func (af *AtomicFile) mode() os.FileMode {
+	if af.Mode != 0 {
+		return af.Mode
+	}
+	if info, err := os.Stat(af.FileName); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
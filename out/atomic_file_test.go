@@ -0,0 +1,75 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kelindar/genny/out"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicFileWrites(t *testing.T) {
+	defer tearDown()
+	if err := ioutil.WriteFile(testFileName, []byte("Old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	af := out.AtomicFile{FileName: testFileName}
+	af.Write([]byte("Word1"))
+	af.Write([]byte("Word2"))
+	assertFileContains(t, "Old")
+
+	assert.NoError(t, af.Close())
+	assertFileContains(t, "Word1Word2")
+	assertNoTempFiles(t)
+}
+
+func TestAtomicFileAbort(t *testing.T) {
+	defer tearDown()
+	if err := ioutil.WriteFile(testFileName, []byte("Old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	af := out.AtomicFile{FileName: testFileName}
+	af.Write([]byte("Half"))
+	assert.NoError(t, af.Abort())
+	assert.NoError(t, af.Close())
+	assertFileContains(t, "Old")
+	assertNoTempFiles(t)
+}
+
+func TestAtomicFileNoWrite(t *testing.T) {
+	defer tearDown()
+	af := out.AtomicFile{FileName: testFileName}
+	assert.NoError(t, af.Close())
+	_, err := os.Stat(testFileName)
+	assert.True(t, os.IsNotExist(err), "Expected file not to be created")
+}
+
+func TestAtomicFileFailedWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the directory of the file cannot be created, since a file is in the way
+	blocker := filepath.Join(dir, "blocker")
+	if err := ioutil.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	af := out.AtomicFile{FileName: filepath.Join(blocker, "file.go")}
+	_, err = af.Write([]byte("Word1"))
+	assert.Error(t, err)
+	assert.Error(t, af.Close())
+}
+
+func assertNoTempFiles(t *testing.T) {
+	matches, err := filepath.Glob("." + testFileName + ".tmp*")
+	if err != nil {
+		panic(err)
+	}
+	assert.Empty(t, matches, "Expected temporary files to be removed")
+}
@@ -0,0 +1,51 @@
+package out
+
+import (
+	"bytes"
+	"io/ioutil"
+)
+
+// ChangedFile is an io.WriteCloser which buffers everything written to it and only replaces
+// the file on Close if its contents differ, so that regenerating identical code neither bumps
+// the modification time of the file nor triggers rebuilds. The file is replaced atomically by
+// renaming a temporary file next to it, and is not created at all if nothing is written.
+type ChangedFile struct {
+	// FileName is path to the file to which genny will write.
+	FileName string
+	buffer   *bytes.Buffer
+}
+
+// Write buffers the bytes to be written to the file on Close.
+func (cf *ChangedFile) Write(p []byte) (int, error) {
+	if cf.buffer == nil {
+		cf.buffer = new(bytes.Buffer)
+	}
+	return cf.buffer.Write(p)
+}
+
+// Close replaces the file with the buffered contents, unless nothing was written or the file
+// already contains them.
+func (cf *ChangedFile) Close() error {
+	if cf.buffer == nil {
+		return nil
+	}
+	content := cf.buffer.Bytes()
+	cf.buffer = nil
+
+	if existing, err := ioutil.ReadFile(cf.FileName); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+
+	af := AtomicFile{FileName: cf.FileName}
+	if _, err := af.Write(content); err != nil {
+		af.Abort()
+		return err
+	}
+	return af.Close()
+}
+
+// Abort discards everything written so far, leaving the file untouched.
+func (cf *ChangedFile) Abort() error {
+	cf.buffer = nil
+	return nil
+}
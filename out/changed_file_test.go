@@ -0,0 +1,59 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelindar/genny/out"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedFileWrites(t *testing.T) {
+	defer tearDown()
+	cf := out.ChangedFile{FileName: testFileName}
+	cf.Write([]byte("Word1"))
+	cf.Write([]byte("Word2"))
+	_, err := os.Stat(testFileName)
+	assert.True(t, os.IsNotExist(err), "Expected file not to be created before Close")
+
+	assert.NoError(t, cf.Close())
+	assertFileContains(t, "Word1Word2")
+}
+
+func TestChangedFileUnchanged(t *testing.T) {
+	defer tearDown()
+	if err := ioutil.WriteFile(testFileName, []byte("Word1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(testFileName, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := out.ChangedFile{FileName: testFileName}
+	cf.Write([]byte("Word1"))
+	assert.NoError(t, cf.Close())
+	info, err := os.Stat(testFileName)
+	if assert.NoError(t, err) {
+		assert.True(t, info.ModTime().Equal(old), "Expected unchanged file not to be rewritten")
+	}
+
+	cf = out.ChangedFile{FileName: testFileName}
+	cf.Write([]byte("Word2"))
+	assert.NoError(t, cf.Close())
+	assertFileContains(t, "Word2")
+	info, err = os.Stat(testFileName)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Expected file mode to be kept")
+	}
+}
+
+func TestChangedFileNoWrite(t *testing.T) {
+	defer tearDown()
+	cf := out.ChangedFile{FileName: testFileName}
+	assert.NoError(t, cf.Close())
+	_, err := os.Stat(testFileName)
+	assert.True(t, os.IsNotExist(err), "Expected file not to be created")
+}
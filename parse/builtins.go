@@ -40,6 +40,89 @@ var Numbers = []string{
 	"uint8",
 }
 
+// Integers contains a slice of all built-in integer types.
+var Integers = []string{
+	"int",
+	"int16",
+	"int32",
+	"int64",
+	"int8",
+	"uint",
+	"uint16",
+	"uint32",
+	"uint64",
+	"uint8",
+	"uintptr",
+}
+
+// Signed contains a slice of all built-in signed integer types.
+var Signed = []string{
+	"int",
+	"int16",
+	"int32",
+	"int64",
+	"int8",
+}
+
+// Unsigned contains a slice of all built-in unsigned integer types.
+var Unsigned = []string{
+	"uint",
+	"uint16",
+	"uint32",
+	"uint64",
+	"uint8",
+	"uintptr",
+}
+
+// Floats contains a slice of all built-in floating-point types.
+var Floats = []string{
+	"float32",
+	"float64",
+}
+
+// Ordered contains a slice of all built-in types which support the < operator.
+var Ordered = []string{
+	"float32",
+	"float64",
+	"int",
+	"int16",
+	"int32",
+	"int64",
+	"int8",
+	"string",
+	"uint",
+	"uint16",
+	"uint32",
+	"uint64",
+	"uint8",
+	"uintptr",
+}
+
+// Comparable contains a slice of all built-in types which support the ==
+// operator, which is every built-in type.
+var Comparable = []string{
+	"bool",
+	"byte",
+	"complex128",
+	"complex64",
+	"error",
+	"float32",
+	"float64",
+	"int",
+	"int16",
+	"int32",
+	"int64",
+	"int8",
+	"rune",
+	"string",
+	"uint",
+	"uint16",
+	"uint32",
+	"uint64",
+	"uint8",
+	"uintptr",
+}
+
 // isNumber returns whether the string is a number
 func isNumber(v string) bool {
 	for _, t := range Numbers {
@@ -0,0 +1,314 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// typeCheck generates the code for every type set separately and type-checks
+// it together with the other files of the package it will be written to, so
+// that an instantiation which does not compile can be traced back to its type
+// set and to the offending position in the template. The outFile is the file
+// the code will be written to (or empty for the current directory); it is
+// left out of the check, since it is about to be replaced.
+func (g *Generator) typeCheck(filename string, in io.ReadSeeker, typeSets []map[string]string, outFile string) error {
+
+	// ensure we are at the beginning of the file
+	in.Seek(0, os.SEEK_SET)
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return sourceError(err)
+	}
+
+	dir := filepath.Dir(outFile)
+	if outFile == "" {
+		outFile = "genny_check.go"
+	}
+	files := []templateFile{{name: filename, source: source}}
+	return g.checkTemplates(files, []string{outFile}, dir, typeSets)
+}
+
+// checkTemplates generates every type set separately for the template files,
+// and type-checks the generated code written to the corresponding outFiles
+// together with the other files of the package in dir.
+func (g *Generator) checkTemplates(files []templateFile, outFiles []string, dir string, typeSets []map[string]string) error {
+	tfs := token.NewFileSet()
+	templates, err := parseTemplates(tfs, files)
+	if err != nil {
+		return err
+	}
+	for _, typeSet := range typeSets {
+		if err := g.checkTypeSet(files, tfs, templates, outFiles, dir, typeSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTemplates parses the template files, so that errors can be mapped back
+// to them.
+func parseTemplates(tfs *token.FileSet, files []templateFile) ([]*ast.File, error) {
+	templates := make([]*ast.File, len(files))
+	for i, file := range files {
+		template, err := parseTemplate(tfs, file)
+		if err != nil {
+			return nil, err
+		}
+		templates[i] = template
+	}
+	return templates, nil
+}
+
+// checkTypeSet generates the type set for the parsed template files and
+// type-checks it like checkTemplates.
+func (g *Generator) checkTypeSet(files []templateFile, tfs *token.FileSet, templates []*ast.File, outFiles []string, dir string, typeSet map[string]string) error {
+	outputs := make([][]byte, len(files))
+	for i, file := range files {
+		output, err := g.generate(file.name, bytes.NewReader(file.source), []map[string]string{typeSet}, siblings(files, i))
+		if err != nil {
+			return err
+		}
+		outputs[i] = output
+	}
+
+	fs := token.NewFileSet()
+	generated, typeErr, err := checkGenerated(fs, dir, outFiles, outputs)
+	if err != nil || typeErr == nil {
+		return err
+	}
+
+	pos := fs.Position(typeErr.Pos)
+	source := files[0].source
+	for i, outFile := range outFiles {
+		if filepath.Clean(outFile) != filepath.Clean(pos.Filename) {
+			continue
+		}
+		source = files[i].source
+		if mapped, ok := templatePosition(tfs, templates[i], fs, generated[i], typeErr.Pos); ok {
+			pos = mapped
+		}
+	}
+
+	generic := blameGeneric(source, pos, typeSet)
+	return &TypeCheckError{
+		Generic:  generic,
+		Specific: typeSet[generic],
+		TypeSet:  typeSet,
+		Pos:      pos,
+		Err:      *typeErr,
+	}
+}
+
+// checkGenerated type-checks the generated code, which is written to the
+// outFiles, along with the other files of the package in dir and returns the
+// first error found in the generated code. Generated test files are checked
+// along with the tests of the package, while external test packages are left
+// out, since they import the package rather than being part of it.
+func checkGenerated(fs *token.FileSet, dir string, outFiles []string, outputs [][]byte) ([]*ast.File, *types.Error, error) {
+	generated := make([]*ast.File, len(outputs))
+	for i, output := range outputs {
+		file, err := parser.ParseFile(fs, outFiles[i], output, 0)
+		if err != nil {
+			return nil, nil, sourceError(err)
+		}
+		generated[i] = file
+	}
+
+	pkgName, tests := generated[0].Name.Name, false
+	for i, file := range generated {
+		if isTestFile(outFiles[i]) {
+			tests = true
+		} else {
+			pkgName = file.Name.Name
+		}
+	}
+
+	var checked []*ast.File
+	inGenerated := make(map[string]bool, len(outFiles))
+	for i, file := range generated {
+		if file.Name.Name == pkgName {
+			checked = append(checked, file)
+			inGenerated[filepath.Clean(outFiles[i])] = true
+		}
+	}
+
+	files, err := parsePackage(fs, dir, pkgName, tests, outFiles...)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append(checked, files...)
+
+	var first *types.Error
+	conf := types.Config{
+		Importer: sharedImporter,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok && first == nil &&
+				inGenerated[filepath.Clean(fs.Position(typeErr.Pos).Filename)] {
+				first = &typeErr
+			}
+		},
+	}
+	conf.Check(pkgName, fs, files, nil)
+	return generated, first, nil
+}
+
+// parsePackage parses the files of the package in dir which belong to the
+// package with the specified name, including its tests if tests is set and
+// leaving out the excluded files. A missing
+// directory is an empty package, since the output creates it. Files which
+// cannot be parsed are left out as well, and the first error is returned
+// along with the files which could.
+func parsePackage(fs *token.FileSet, dir, pkgName string, tests bool, exclude ...string) ([]*ast.File, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	// the files of other packages, such as templates living next to the
+	// output, are filtered out below
+	pkg, first := build.ImportDir(dir, 0)
+	switch first.(type) {
+	case nil, *build.NoGoError, *build.MultiplePackageError:
+		first = nil
+	default:
+		if pkg == nil {
+			return nil, first
+		}
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		excluded[filepath.Clean(path)] = true
+	}
+
+	names := append(pkg.GoFiles, pkg.InvalidGoFiles...)
+	if tests {
+		names = append(names, pkg.TestGoFiles...)
+	}
+
+	var files []*ast.File
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if excluded[filepath.Clean(path)] {
+			continue
+		}
+		file, err := parser.ParseFile(fs, path, nil, 0)
+		if err != nil {
+			if first == nil {
+				first = sourceError(err)
+			}
+			continue
+		}
+		if file.Name.Name == pkgName {
+			files = append(files, file)
+		}
+	}
+	return files, first
+}
+
+// templatePosition maps a position in the generated code back to the template.
+// Both engines keep the top level declarations in order, so the declaration
+// containing the position is matched by index and the line offset within it
+// is carried over. A syntax error may cut its declaration short, so the
+// position is matched with the last declaration starting before it.
+func templatePosition(tfs *token.FileSet, template *ast.File, fs *token.FileSet, generated *ast.File, pos token.Pos) (token.Position, bool) {
+	tdecls, gdecls := topLevelDecls(template, true), topLevelDecls(generated, false)
+	if len(tdecls) != len(gdecls) {
+		return token.Position{}, false
+	}
+
+	for i := len(gdecls) - 1; i >= 0; i-- {
+		decl := gdecls[i]
+		if pos < decl.Pos() {
+			continue
+		}
+
+		start, at := fs.Position(decl.Pos()), fs.Position(pos)
+		mapped := tfs.Position(tdecls[i].Pos())
+		mapped.Line += at.Line - start.Line
+		mapped.Column = at.Column
+		mapped.Offset = -1
+		return mapped, true
+	}
+	return token.Position{}, false
+}
+
+// topLevelDecls gets the declarations of a file which end up in the generated
+// code, optionally skipping the declarations of the generic types.
+func topLevelDecls(file *ast.File, skipGeneric bool) []ast.Decl {
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok {
+			if gen.Tok == token.IMPORT || (skipGeneric && isGenericDecl(gen)) {
+				continue
+			}
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// isGenericDecl returns whether the declaration only declares generic types,
+// in which case it is removed from the generated code.
+func isGenericDecl(decl *ast.GenDecl) bool {
+	if decl.Tok != token.TYPE || len(decl.Specs) == 0 {
+		return false
+	}
+	for _, spec := range decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); !ok || !isGenericTypeDefinition(ts) {
+			return false
+		}
+	}
+	return true
+}
+
+// blameGeneric gets the generic type which is mentioned closest to the
+// position in the template. If the line does not mention any generic type,
+// the only generic type of the type set is returned, if there is one.
+func blameGeneric(source []byte, pos token.Position, typeSet map[string]string) string {
+	lines := strings.Split(string(source), "\n")
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return soleGeneric(typeSet)
+	}
+
+	line, blamed, distance := lines[pos.Line-1], "", -1
+	for generic := range typeSet {
+		for i := 0; i < len(line); {
+			at := indexBoundary(line[i:], generic)
+			if at < 0 {
+				break
+			}
+			at += i
+			d := at - (pos.Column - 1)
+			if d < 0 {
+				d = -d
+			}
+			if distance < 0 || d < distance || (d == distance && generic < blamed) {
+				blamed, distance = generic, d
+			}
+			i = at + len(generic)
+		}
+	}
+
+	if blamed == "" {
+		return soleGeneric(typeSet)
+	}
+	return blamed
+}
+
+// soleGeneric gets the generic type of a type set with a single entry.
+func soleGeneric(typeSet map[string]string) string {
+	for generic := range typeSet {
+		if len(typeSet) == 1 {
+			return generic
+		}
+	}
+	return ""
+}
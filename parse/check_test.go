@@ -0,0 +1,75 @@
+package parse_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		in := strings.NewReader(contents("test/check/generic_equal.go"))
+		err := parse.NewGenerator(parse.Options{Engine: engine, TypeCheck: true}).Validate("test/check/generic_equal.go", in, []map[string]string{{"Something": "int"}, {"Something": "string"}}, "test/check/gen_equal.go")
+		assert.NoError(t, err, engine.String())
+
+		err = parse.NewGenerator(parse.Options{Engine: engine, TypeCheck: true}).Validate("test/check/generic_equal.go", in, []map[string]string{{"Something": "int"}, {"Something": "Ints:[]int"}}, "test/check/gen_equal.go")
+		if assert.Error(t, err, engine.String()) {
+			assert.Contains(t, err.Error(), "test/check/generic_equal.go:9:")
+			assert.Contains(t, err.Error(), "Something=Ints:[]int")
+		}
+	}
+}
+
+func TestCheckMixedPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"other.go": "package other\n", "used.go": "package check\n\ntype Ints []int\n"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in := strings.NewReader(contents("test/check/generic_equal.go"))
+	err = parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, PkgName: "check", TypeCheck: true}).Validate("test/check/generic_equal.go", in, []map[string]string{{"Something": "int"}}, filepath.Join(dir, "gen_equal.go"))
+	assert.NoError(t, err)
+
+	err = parse.NewGenerator(parse.Options{PkgName: "check"}).Validate("test/check/generic_equal.go", in, []map[string]string{{"Something": "Ints"}}, filepath.Join(dir, "gen_equal.go"))
+	assert.NoError(t, err)
+}
+
+func TestCheckTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the declarations of the tests must not clash across the type sets
+	template := contents("test/tests/generic_queue.go")
+	typeSets := []map[string]string{{"Something": "int"}, {"Something": "string"}}
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		g := parse.NewGenerator(parse.Options{Engine: engine, Tests: true, TypeCheck: true})
+		err := g.ValidateTests("generic_queue.go", strings.NewReader(template), "generic_queue_test.go",
+			strings.NewReader(contents("test/tests/generic_queue_tests.go")), typeSets, filepath.Join(dir, "queue.go"))
+		assert.NoError(t, err, engine.String())
+	}
+
+	tests := "package tests\n\nimport \"testing\"\n\nfunc TestZero(t *testing.T) {\n\tvar zero Something = 0\n\tt.Log(NewSomethingQueue(), zero)\n}\n"
+	g := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, Tests: true, TypeCheck: true})
+	err = g.ValidateTests("generic_queue.go", strings.NewReader(template), "generic_queue_test.go", strings.NewReader(tests), typeSets, filepath.Join(dir, "queue.go"))
+	var typeCheck *parse.TypeCheckError
+	if assert.True(t, errors.As(err, &typeCheck), "%v", err) {
+		assert.Equal(t, "generic_queue_test.go", typeCheck.Pos.Filename)
+		assert.Equal(t, 6, typeCheck.Pos.Line)
+		assert.Equal(t, "string", typeCheck.Specific)
+	}
+}
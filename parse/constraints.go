@@ -0,0 +1,348 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// checkConstraints makes sure the specific types satisfy the constraints of
+// the generic types they replace. A generic type declared as an interface
+// embedding generic.Type, such as
+//
+//     type Stringer interface {
+//         generic.Type
+//         fmt.Stringer
+//     }
+//
+// requires its specific types to implement the remaining methods, while the
+// other marker types (such as generic.Number or generic.Comparable) require
+// specific types which support the corresponding operations, including named
+// types whose underlying type does. Specific types
+// are resolved in the package the code will be written to, which is the
+// directory of outFile (or the current directory if it is empty). Specific
+// types which cannot be resolved are not checked.
+func checkConstraints(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]string, importPaths []string, outFile string) error {
+	c, err := newConstraintChecker(filename, pkgName, in, importPaths)
+	if err != nil {
+		return err
+	}
+	return c.check(typeSets, outFile)
+}
+
+// constraintChecker checks type sets against the constraints of a template,
+// which is only parsed and type-checked once however many type sets there are.
+type constraintChecker struct {
+	markers     map[string]marker
+	constraints map[string]*types.Interface
+	generics    []string // the generics whose specific types are resolved
+	pkgName     string
+	paths       []string
+	scope       *typeScope
+}
+
+// newConstraintChecker parses and type-checks the template.
+func newConstraintChecker(filename, pkgName string, in io.ReadSeeker, importPaths []string) (*constraintChecker, error) {
+
+	// ensure we are at the beginning of the file
+	in.Seek(0, os.SEEK_SET)
+
+	// parse the source file
+	fs := token.NewFileSet()
+	file, err := parser.ParseFile(fs, filename, in, 0)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+
+	template := newTypedTemplate(fs, []*ast.File{file})
+	c := &constraintChecker{
+		markers:     genericMarkers(fs, file),
+		constraints: template.interfaceConstraints(),
+		pkgName:     pkgName,
+	}
+	for generic, m := range c.markers {
+		if _, ok := markerConstraints[m.name]; ok || c.constraints[generic] != nil {
+			c.generics = append(c.generics, generic)
+		}
+	}
+	sort.Strings(c.generics)
+
+	if c.pkgName == "" {
+		c.pkgName = file.Name.Name
+	}
+	paths := stringArraySet(nil)
+	for _, path := range importPaths {
+		paths = paths.append(path)
+	}
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil && !isGenericImport(path) {
+			paths = paths.append(path)
+		}
+	}
+	c.paths = paths
+	return c, nil
+}
+
+// check makes sure the specific types of the type sets satisfy the
+// constraints. The specific types are resolved in the package of the outFile
+// the first time they need to be, and every later outFile is expected to be
+// written to the same package.
+func (c *constraintChecker) check(typeSets []map[string]string, outFile string) error {
+	if err := checkMarkers(c.markers, typeSets); err != nil {
+		return err
+	}
+	if len(c.generics) == 0 {
+		return nil
+	}
+	if c.scope == nil {
+		scope, err := newTypeScope(filepath.Dir(outFile), outFile, c.pkgName, c.paths)
+		if err != nil {
+			return err
+		}
+		c.scope = scope
+	}
+
+	for _, typeSet := range typeSets {
+		for _, generic := range c.generics {
+			specificType, ok := typeSet[generic]
+			if !ok {
+				continue
+			}
+			typ := c.scope.lookup(typify(specificType))
+			if typ == nil {
+				continue
+			}
+			m := c.markers[generic]
+			if constraint, ok := markerConstraints[m.name]; ok && !constraint.satisfiedBy(typ) {
+				return &ConstraintError{
+					Generic:    generic,
+					Specific:   specificType,
+					Constraint: m.name,
+					Pos:        m.pos,
+					TypeSet:    typeSet,
+				}
+			}
+			iface, ok := c.constraints[generic]
+			if !ok {
+				continue
+			}
+			if missing := missingMethods(typ, iface, c.scope.qualifier); len(missing) > 0 {
+				return &ConstraintError{
+					Generic:  generic,
+					Specific: specificType,
+					Missing:  missing,
+					Pos:      m.pos,
+					TypeSet:  typeSet,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// interfaceConstraints gets the method sets required by the generic types
+// which are declared as interfaces.
+func (t *typedTemplate) interfaceConstraints() map[string]*types.Interface {
+	constraints := make(map[string]*types.Interface)
+	for obj, spec := range t.generics {
+		if _, ok := spec.Type.(*ast.InterfaceType); !ok {
+			continue
+		}
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+			constraints[spec.Name.Name] = iface
+		}
+	}
+	return constraints
+}
+
+// missingMethods gets the methods of the interface which are not implemented
+// by the type.
+func missingMethods(typ types.Type, iface *types.Interface, qualifier types.Qualifier) []string {
+	var missing []string
+	methods := types.NewMethodSet(typ)
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		if sel := methods.Lookup(method.Pkg(), method.Name()); sel != nil && types.Identical(sel.Type(), method.Type()) {
+			continue
+		}
+		signature := types.TypeString(method.Type(), qualifier)
+		missing = append(missing, method.Name()+strings.TrimPrefix(signature, "func"))
+	}
+	return missing
+}
+
+// typeScope resolves type expressions in the context of a package.
+type typeScope struct {
+	fset *token.FileSet
+	pkg  *types.Package
+	pos  token.Pos
+}
+
+// newTypeScope type-checks the package in dir, along with an additional file
+// which imports the specified packages so that qualified types resolve.
+func newTypeScope(dir, exclude, pkgName string, importPaths []string) (*typeScope, error) {
+	fs := token.NewFileSet()
+	// the scope is only used to look up types, so the files which do not
+	// parse, such as an output file the shell has just created empty, are
+	// left out rather than failing the generation
+	files, _ := parsePackage(fs, dir, pkgName, false, exclude)
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "package %s\n", pkgName)
+	for _, imp := range importPaths {
+		fmt.Fprintf(&src, "import %q\n", imp)
+	}
+	imports, err := parser.ParseFile(fs, filepath.Join(dir, "genny_imports.go"), src.Bytes(), 0)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+
+	conf := types.Config{
+		Importer: sharedImporter,
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(pkgName, fs, append(files, imports), nil)
+	return &typeScope{fset: fs, pkg: pkg, pos: imports.Package}, nil
+}
+
+// lookup gets the type denoted by the expression, or nil if it cannot be resolved.
+func (s *typeScope) lookup(expr string) types.Type {
+	tv, err := types.Eval(s.fset, s.pkg, s.pos, expr)
+	if err != nil || !tv.IsType() || tv.Type == types.Typ[types.Invalid] {
+		return nil
+	}
+	return tv.Type
+}
+
+// qualifier writes the types of the package unqualified and every other
+// type qualified with its package name.
+func (s *typeScope) qualifier(pkg *types.Package) string {
+	if pkg == s.pkg {
+		return ""
+	}
+	return pkg.Name()
+}
+
+// marker is the marker type a generic type is declared with.
+type marker struct {
+	name string         // name of the marker type, such as generic.Number
+	pos  token.Position // position of the declaration of the generic type
+}
+
+// genericMarkers gets the marker type, such as generic.Type or generic.Number,
+// each generic type of the file is declared with. This includes aliases of the
+// marker types, such as "type number = generic.Number".
+func genericMarkers(fs *token.FileSet, file *ast.File) map[string]marker {
+	markers := make(map[string]marker)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !isGenericTypeDefinition(ts) {
+				continue
+			}
+			m := marker{name: genericType, pos: fs.Position(ts.Pos())}
+			if sel, ok := ts.Type.(*ast.SelectorExpr); ok {
+				m.name = genericPackage + "." + sel.Sel.Name
+			}
+			markers[ts.Name.Name] = m
+		}
+	}
+	return markers
+}
+
+// markerConstraint describes which specific types are accepted by one of the
+// marker types of the generic package.
+type markerConstraint struct {
+	// basic is whether only basic types (and named types based on them) satisfy it
+	basic bool
+
+	// satisfiedBy returns whether a resolved type satisfies the constraint
+	satisfiedBy func(types.Type) bool
+}
+
+// markerConstraints contains the constraints implied by the marker types. The
+// generic.Type marker accepts any type and is therefore not listed.
+var markerConstraints = map[string]markerConstraint{
+	genericNumber:        {basic: true, satisfiedBy: isBasicType(types.IsInteger | types.IsFloat)},
+	"generic.Integer":    {basic: true, satisfiedBy: isBasicType(types.IsInteger)},
+	"generic.Float":      {basic: true, satisfiedBy: isBasicType(types.IsFloat)},
+	"generic.Ordered":    {basic: true, satisfiedBy: isBasicType(types.IsOrdered)},
+	"generic.Comparable": {basic: false, satisfiedBy: types.Comparable},
+}
+
+// isBasicType returns a function which reports whether the underlying type of
+// a type is a basic type with any of the specified properties.
+func isBasicType(info types.BasicInfo) func(types.Type) bool {
+	return func(typ types.Type) bool {
+		basic, ok := typ.Underlying().(*types.Basic)
+		return ok && basic.Info()&info != 0
+	}
+}
+
+// checkMarkers makes sure the specific types satisfy the marker types of the
+// generic types, as far as this can be told without type information.
+func checkMarkers(markers map[string]marker, typeSets []map[string]string) error {
+	generics := make([]string, 0, len(markers))
+	for generic := range markers {
+		generics = append(generics, generic)
+	}
+	sort.Strings(generics)
+
+	for _, typeSet := range typeSets {
+		for _, generic := range generics {
+			m := markers[generic]
+			constraint, ok := markerConstraints[m.name]
+			specificType, found := typeSet[generic]
+			if !ok || !found {
+				continue
+			}
+			if !constraint.maySatisfy(typify(specificType)) {
+				return &ConstraintError{
+					Generic:    generic,
+					Specific:   specificType,
+					Constraint: m.name,
+					Pos:        m.pos,
+					TypeSet:    typeSet,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maySatisfy returns whether the type expression could satisfy the constraint.
+// Types which are built from predeclared types only are checked exactly, while
+// other named types are assumed to be acceptable, since their underlying type
+// is unknown.
+func (c markerConstraint) maySatisfy(expr string) bool {
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		return false
+	}
+	if tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, expr); err == nil && tv.IsType() {
+		return c.satisfiedBy(tv.Type)
+	}
+
+	switch v := parsed.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	case *ast.MapType, *ast.FuncType:
+		return false
+	case *ast.ArrayType:
+		return !c.basic && v.Len != nil
+	}
+	return !c.basic
+}
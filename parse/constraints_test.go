@@ -0,0 +1,175 @@
+package parse_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConstraints(t *testing.T) {
+	in := strings.NewReader(contents("test/interfaces/join.go"))
+	out := "test/interfaces/join_expected.go"
+
+	err := parse.NewGenerator(parse.Options{}).Validate("join.go", in, []map[string]string{{"Stringer": "MyStr"}}, out)
+	assert.NoError(t, err)
+
+	err = parse.NewGenerator(parse.Options{}).Validate("join.go", in, []map[string]string{{"Stringer": "*MyStr"}}, out)
+	assert.NoError(t, err)
+
+	err = parse.NewGenerator(parse.Options{}).Validate("join.go", in, []map[string]string{{"Stringer": "MyStr"}, {"Stringer": "int"}}, out)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Specific type 'int' does not implement 'Stringer', missing methods: String() string", err.Error())
+	}
+
+	// specific types which cannot be resolved are not checked
+	err = parse.NewGenerator(parse.Options{}).Validate("join.go", in, []map[string]string{{"Stringer": "Unknown"}}, out)
+	assert.NoError(t, err)
+
+	// templates without interface constraints are always fine
+	in = strings.NewReader(contents("test/queue/generic_queue.go"))
+	err = parse.NewGenerator(parse.Options{}).Validate("generic_queue.go", in, []map[string]string{{"Something": "int"}}, "")
+	assert.NoError(t, err)
+}
+
+func TestNumberConstraint(t *testing.T) {
+	alias := `package example
+
+import "github.com/kelindar/genny/generic"
+
+type number = generic.Number
+
+func Number() number {
+	return number(0)
+}
+`
+	for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+		for _, in := range []string{contents("test/numbers/generic_number.go"), alias} {
+			generic := "NumberType"
+			if in == alias {
+				generic = "number"
+			}
+
+			for _, specific := range []string{"int", "float32", "uintptr", "byte", "MyFloat", "time.Duration"} {
+				_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("numbers.go", strings.NewReader(in), []map[string]string{{generic: specific}})
+				assert.NoError(t, err, "%v: %s", engine, specific)
+			}
+
+			for _, specific := range []string{"string", "bool", "complex64", "[]int", "*int", "map[string]int", "interface{}", "Name:string"} {
+				_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("numbers.go", strings.NewReader(in), []map[string]string{{generic: "int"}, {generic: specific}})
+				if assert.Error(t, err, "%v: %s", engine, specific) {
+					assert.Equal(t, "Specific type '"+specific+"' for '"+generic+"' does not satisfy generic.Number", err.Error())
+				}
+			}
+		}
+	}
+
+	// named types are resolved in the output package
+	in := strings.NewReader(contents("test/numbers/generic_number.go"))
+	out := "test/numbers/int_number.go"
+	err := parse.NewGenerator(parse.Options{}).Validate("generic_number.go", in, []map[string]string{{"NumberType": "MyFloat"}}, out)
+	assert.NoError(t, err)
+
+	err = parse.NewGenerator(parse.Options{}).Validate("generic_number.go", in, []map[string]string{{"NumberType": "MyName"}}, out)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Specific type 'MyName' for 'NumberType' does not satisfy generic.Number", err.Error())
+	}
+
+	// files which do not parse, such as an output the shell has just created
+	// empty, are left out of the output package
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "custom_types.go"), []byte(contents("test/numbers/custom_types.go")), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "gen_number.go"), nil, 0644))
+	err = parse.NewGenerator(parse.Options{}).Validate("generic_number.go", in, []map[string]string{{"NumberType": "MyFloat"}}, filepath.Join(dir, "int_number.go"))
+	assert.NoError(t, err)
+}
+
+func TestMarkerConstraints(t *testing.T) {
+	valid := map[string]string{"Key": "string", "Value": "int", "Count": "uint8", "Ratio": "float32"}
+	for _, tc := range []struct {
+		generic  string
+		specific string
+		ok       bool
+	}{
+		{"Key", "[2]int", true},
+		{"Key", "*int", true},
+		{"Key", "MyType", true},
+		{"Key", "struct{ a int }", true},
+		{"Key", "[]int", false},
+		{"Key", "map[string]int", false},
+		{"Key", "func()", false},
+		{"Key", "[]MyType", false},
+		{"Key", "struct{ a []int }", false},
+		{"Value", "string", true},
+		{"Value", "float64", true},
+		{"Value", "bool", false},
+		{"Value", "complex128", false},
+		{"Count", "uintptr", true},
+		{"Count", "rune", true},
+		{"Count", "float32", false},
+		{"Ratio", "float64", true},
+		{"Ratio", "int", false},
+	} {
+		typeSet := make(map[string]string)
+		for generic, specific := range valid {
+			typeSet[generic] = specific
+		}
+		typeSet[tc.generic] = tc.specific
+
+		for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+			in := strings.NewReader(contents("test/markers/generic_markers.go"))
+			_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("generic_markers.go", in, []map[string]string{typeSet})
+			if tc.ok {
+				assert.False(t, err != nil && strings.Contains(err.Error(), "does not satisfy"), "%v: %s=%s: %v", engine, tc.generic, tc.specific, err)
+			} else if assert.Error(t, err, "%v: %s=%s", engine, tc.generic, tc.specific) {
+				assert.Contains(t, err.Error(), "Specific type '"+tc.specific+"' for '"+tc.generic+"' does not satisfy generic.")
+			}
+		}
+	}
+
+	// named types are resolved in the output package
+	in := strings.NewReader(contents("test/markers/generic_markers.go"))
+	out := "test/markers/string_int_markers.go"
+	err := parse.NewGenerator(parse.Options{}).Validate("generic_markers.go", in, []map[string]string{{"Key": "Score", "Value": "Score", "Count": "Score", "Ratio": "float64"}}, out)
+	assert.NoError(t, err)
+
+	err = parse.NewGenerator(parse.Options{}).Validate("generic_markers.go", in, []map[string]string{{"Key": "Names", "Value": "int", "Count": "int", "Ratio": "float64"}}, out)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Specific type 'Names' for 'Key' does not satisfy generic.Comparable", err.Error())
+	}
+}
+
+func TestValidateEach(t *testing.T) {
+	in := strings.NewReader(contents("test/interfaces/join.go"))
+	out := "test/interfaces/join_expected.go"
+	it, err := parse.IterateTypeSets("Stringer=MyStr,*MyStr,int")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var validated []string
+	err = parse.NewGenerator(parse.Options{}).ValidateEach("join.go", in, it, func(typeSet map[string]string) (string, error) {
+		validated = append(validated, typeSet["Stringer"])
+		return out, nil
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, "Specific type 'int' does not implement 'Stringer', missing methods: String() string", err.Error())
+	}
+	assert.Equal(t, []string{"MyStr", "*MyStr", "int"}, validated)
+
+	// every type set is type-checked as well, and outFile can stop the validation
+	it.Reset()
+	calls := 0
+	err = parse.NewGenerator(parse.Options{TypeCheck: true}).ValidateEach("join.go", in, it, func(typeSet map[string]string) (string, error) {
+		if calls++; calls == 2 {
+			return "", errors.New("duplicate file")
+		}
+		return out, nil
+	})
+	assert.EqualError(t, err, "duplicate file")
+	assert.Equal(t, 2, calls)
+}
@@ -2,46 +2,239 @@ package parse
 
 import (
 	"errors"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 )
 
-// errMissingSpecificType represents an error when a generic type is not
+// MissingSpecificTypeError represents an error when a generic type is not
 // satisfied by a specific type.
-type errMissingSpecificType struct {
+type MissingSpecificTypeError struct {
 	GenericType string
+
+	// Pos is the position of the declaration of the generic type.
+	Pos token.Position
+
+	// TypeSet is the type set which lacks the generic type.
+	TypeSet map[string]string
 }
 
 // Error gets a human readable string describing this error.
-func (e errMissingSpecificType) Error() string {
+func (e MissingSpecificTypeError) Error() string {
 	return "Missing specific type for '" + e.GenericType + "' generic type"
 }
 
-// errImports represents an error from goimports.
-type errImports struct {
+// ImportsError represents an error from goimports.
+type ImportsError struct {
 	Err error
+
+	// Pos is the position in the template the error was traced back to, if
+	// known.
+	Pos token.Position
+
+	// TypeSet is the type set whose code has the error, if known.
+	TypeSet map[string]string
 }
 
 // Error gets a human readable string describing this error.
-func (e errImports) Error() string {
+func (e ImportsError) Error() string {
 	return "Failed to goimports the generated code: " + e.Err.Error()
 }
 
-// errSource represents an error with the source file.
-type errSource struct {
+// Unwrap gets the error returned by goimports.
+func (e ImportsError) Unwrap() error {
+	return e.Err
+}
+
+// SourceError represents an error with the source file.
+type SourceError struct {
 	Err error
+
+	// Pos is the position of the error in the source file, if known.
+	Pos token.Position
 }
 
 // Error gets a human readable string describing this error.
-func (e errSource) Error() string {
+func (e SourceError) Error() string {
 	return "Failed to parse source file: " + e.Err.Error()
 }
 
-type errBadTypeArgs struct {
+// Unwrap gets the error encountered while reading or parsing the source.
+func (e SourceError) Unwrap() error {
+	return e.Err
+}
+
+// TypeCheckError represents an error when the code generated for a type set
+// does not compile.
+type TypeCheckError struct {
+	Generic  string
+	Specific string
+	TypeSet  map[string]string
+
+	// Pos is the position of the error in the template, or in the generated
+	// code if it cannot be mapped back to the template.
+	Pos token.Position
+	Err error
+}
+
+// Error gets a human readable string describing this error.
+func (e TypeCheckError) Error() string {
+	instance := e.Generic + "=" + e.Specific
+	if e.Generic == "" {
+		instance = FormatTypeSet(e.TypeSet)
+	}
+	message := e.Err.Error()
+	if typeErr, ok := e.Err.(types.Error); ok {
+		message = typeErr.Msg
+	}
+	return e.Pos.String() + ": code generated for " + instance + " does not compile: " + message
+}
+
+// Unwrap gets the error reported by the type checker.
+func (e TypeCheckError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintError represents an error when a specific type does not satisfy
+// the constraints of the generic type it replaces.
+type ConstraintError struct {
+	Generic    string
+	Specific   string
+	Constraint string
+	Missing    []string
+
+	// Pos is the position of the declaration of the generic type.
+	Pos token.Position
+
+	// TypeSet is the type set containing the specific type.
+	TypeSet map[string]string
+}
+
+// Error gets a human readable string describing this error.
+func (e ConstraintError) Error() string {
+	if e.Constraint != "" {
+		return "Specific type '" + e.Specific + "' for '" + e.Generic +
+			"' does not satisfy " + e.Constraint
+	}
+	return "Specific type '" + e.Specific + "' does not implement '" + e.Generic +
+		"', missing methods: " + strings.Join(e.Missing, ", ")
+}
+
+// EngineError represents an error when an unknown engine is requested.
+type EngineError struct {
+	Name string
+}
+
+// Error gets a human readable string describing this error.
+func (e EngineError) Error() string {
+	return "Unknown engine '" + e.Name + "', expected legacy, ast or types"
+}
+
+// TypeArgsError represents an error when the type arguments cannot be parsed.
+type TypeArgsError struct {
 	Message string
 	Arg     string
 }
 
-func (e errBadTypeArgs) Error() string {
+// Error gets a human readable string describing this error.
+func (e TypeArgsError) Error() string {
 	return "\"" + e.Arg + "\" is bad: " + e.Message
 }
 
-var errMissingTypeInformation = errors.New("No type arguments were specified and no \"// +gogen\" tag was found in the source.")
+// ErrMissingTypeInformation is returned when no type arguments are available.
+var ErrMissingTypeInformation = errors.New("No type arguments were specified and no \"// +gogen\" tag was found in the source.")
+
+// NoTemplateFilesError represents an error when a template directory does
+// not contain any Go files to generate.
+type NoTemplateFilesError struct {
+	Dir string
+}
+
+// Error gets a human readable string describing this error.
+func (e NoTemplateFilesError) Error() string {
+	return "No template files found in '" + e.Dir + "'"
+}
+
+// HeaderError represents an error when the header template cannot be
+// rendered, or when it does not mark the code as generated.
+type HeaderError struct {
+	Header string
+	Err    error
+}
+
+// Error gets a human readable string describing this error.
+func (e HeaderError) Error() string {
+	if e.Err != nil {
+		return "Invalid header template: " + e.Err.Error()
+	}
+	return "Invalid header template: no line matches \"Code generated ... DO NOT EDIT.\""
+}
+
+// Unwrap gets the error of the template, if any.
+func (e HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// OriginError represents an error when a line of the "// genny:source" block
+// cannot be read.
+type OriginError struct {
+	Line int
+	Text string
+}
+
+// Error gets a human readable string describing this error.
+func (e OriginError) Error() string {
+	return "Invalid genny:source line " + strconv.Itoa(e.Line) + ": " + e.Text
+}
+
+// ErrMissingOrigin is returned when the generated code does not record its
+// origin.
+var ErrMissingOrigin = errors.New("No \"// genny:source\" block was found, the file was not generated with -source.")
+
+// sourceError wraps an error with the source file, taking the position from
+// the first error reported by the parser.
+func sourceError(err error) *SourceError {
+	return &SourceError{Err: err, Pos: errorPosition(err)}
+}
+
+// importsError wraps an error from goimports. The code of every type set is
+// parsed on its own, so that a syntax error is traced back to the type set
+// which caused it and, if the template is known, to its position there.
+func importsError(err error, tfs *token.FileSet, template *ast.File, outputs [][]byte, typeSets []map[string]string) *ImportsError {
+	e := &ImportsError{Err: err}
+	for i, output := range outputs {
+		fs := token.NewFileSet()
+		generated, parseErr := parser.ParseFile(fs, "", output, 0)
+		if parseErr == nil {
+			continue
+		}
+
+		e.TypeSet = typeSets[i]
+		pos := errorPosition(parseErr)
+		if template != nil && generated != nil && generated.Package.IsValid() && pos.IsValid() {
+			at := fs.File(generated.Package).Pos(pos.Offset)
+			if mapped, ok := templatePosition(tfs, template, fs, generated, at); ok {
+				e.Pos = mapped
+			}
+		}
+		break
+	}
+	return e
+}
+
+// errorPosition gets the position of a syntax error, if it has one.
+func errorPosition(err error) token.Position {
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		return list[0].Pos
+	}
+	var scanErr *scanner.Error
+	if errors.As(err, &scanErr) {
+		return scanErr.Pos
+	}
+	return token.Position{}
+}
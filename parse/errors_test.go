@@ -0,0 +1,98 @@
+package parse_test
+
+import (
+	"errors"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingSpecificTypeError(t *testing.T) {
+	typeSets := []map[string]string{{"KeyType": "string"}}
+	for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+		in := strings.NewReader(contents("test/multipletypes/generic_simplemap.go"))
+		_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("generic_simplemap.go", in, typeSets)
+
+		var missing *parse.MissingSpecificTypeError
+		if assert.True(t, errors.As(err, &missing), "%v: %v", engine, err) {
+			assert.Equal(t, "ValueType", missing.GenericType)
+			assert.Equal(t, "generic_simplemap.go", missing.Pos.Filename)
+			assert.Equal(t, 6, missing.Pos.Line)
+			assert.Equal(t, typeSets[0], missing.TypeSet)
+		}
+	}
+
+	// the first generic type declared is reported, every time
+	typeSets = []map[string]string{{"ItemList": "[]int"}}
+	for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+		for i := 0; i < 20; i++ {
+			in := strings.NewReader(contents("test/overlapping/generic_index.go"))
+			_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("generic_index.go", in, typeSets)
+
+			var missing *parse.MissingSpecificTypeError
+			if !assert.True(t, errors.As(err, &missing), "%v: %v", engine, err) || !assert.Equal(t, "Key", missing.GenericType, engine.String()) {
+				break
+			}
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	in := strings.NewReader("package broken\n\nfunc {\n")
+	_, err := parse.NewGenerator(parse.Options{Engine: parse.EngineAst}).Generate("broken.go", in, []map[string]string{{"Something": "int"}})
+
+	var source *parse.SourceError
+	if assert.True(t, errors.As(err, &source), "%v", err) {
+		assert.Equal(t, "broken.go", source.Pos.Filename)
+		assert.Equal(t, 3, source.Pos.Line)
+		assert.NotNil(t, errors.Unwrap(err))
+	}
+}
+
+func TestImportsErrorPosition(t *testing.T) {
+	source := "package q\n\nimport \"github.com/kelindar/genny/generic\"\n\ntype Something generic.Type\n\n" +
+		"func NewSomething() Something {\n\treturn Something{}\n}\n"
+	typeSets := []map[string]string{{"Something": "int"}, {"Something": "chan int"}}
+	for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+		_, err := parse.NewGenerator(parse.Options{Engine: engine}).Generate("q.go", strings.NewReader(source), typeSets)
+
+		// the composite literal of a channel does not parse
+		var imports *parse.ImportsError
+		if assert.True(t, errors.As(err, &imports), "%v: %v", engine, err) {
+			assert.Equal(t, "q.go", imports.Pos.Filename, engine.String())
+			assert.Equal(t, 8, imports.Pos.Line, engine.String())
+			assert.Equal(t, typeSets[1], imports.TypeSet, engine.String())
+		}
+	}
+}
+
+func TestConstraintErrorPosition(t *testing.T) {
+	typeSets := []map[string]string{{"NumberType": "string"}}
+	in := strings.NewReader(contents("test/numbers/generic_number.go"))
+	_, err := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes}).Generate("generic_number.go", in, typeSets)
+
+	var constraint *parse.ConstraintError
+	if assert.True(t, errors.As(err, &constraint), "%v", err) {
+		assert.Equal(t, "generic.Number", constraint.Constraint)
+		assert.Equal(t, "generic_number.go", constraint.Pos.Filename)
+		assert.True(t, constraint.Pos.Line > 0)
+		assert.Equal(t, typeSets[0], constraint.TypeSet)
+	}
+}
+
+func TestTypeCheckErrorUnwrap(t *testing.T) {
+	in := strings.NewReader(contents("test/check/generic_equal.go"))
+	err := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, TypeCheck: true}).Validate("test/check/generic_equal.go", in, []map[string]string{{"Something": "Ints:[]int"}}, "test/check/gen_equal.go")
+
+	var typeCheck *parse.TypeCheckError
+	if assert.True(t, errors.As(err, &typeCheck), "%v", err) {
+		assert.Equal(t, "Something", typeCheck.Generic)
+		assert.Equal(t, 9, typeCheck.Pos.Line)
+
+		var typeErr types.Error
+		assert.True(t, errors.As(err, &typeErr))
+	}
+}
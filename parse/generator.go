@@ -0,0 +1,252 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures the code generated by a Generator.
+type Options struct {
+	// Engine is the implementation used to substitute the specific types.
+	Engine Engine
+
+	// PkgName is the package name of the generated code, or empty to keep the
+	// package name of the template.
+	PkgName string
+
+	// Imports are the import paths added to the generated code.
+	Imports []string
+
+	// StripTag is the build tag whose "// +build" line is left out of the
+	// generated code.
+	StripTag string
+
+	// Header is a text/template of the comment written at the top of the
+	// generated code, executed with HeaderData. It must contain a line such
+	// as "Code generated by mytool from {{.Template}}. DO NOT EDIT." so that
+	// tools recognize the code as generated. If empty, DefaultHeader is used.
+	Header string
+
+	// TemplateVersion is the version of the template, if it was resolved from
+	// a versioned source such as a Go module. It is recorded in the header.
+	TemplateVersion string
+
+	// Origin is recorded in the generated code if set, so that the code can
+	// be regenerated with the same arguments.
+	Origin *Origin
+
+	// Naming gets the name which identifiers use for a specific type, such as
+	// "Int" in IntQueue. Specific types which specify their name with the
+	// Title:Type syntax keep it. If nil, the name is derived from the type.
+	Naming func(specific string) string
+
+	// TypeCheck makes Validate type-check every instantiation against the
+	// package it is written to.
+	TypeCheck bool
+
+	// Tests makes GeneratePackage generate the test files of the template
+	// package as well, and makes the declarations of test files (named
+	// *_test.go) specific to every type set, so that every type set gets its
+	// own tests.
+	Tests bool
+
+	// Hooks are called while the code is generated.
+	Hooks Hooks
+}
+
+// Hooks are called while the code is generated, so that tools built on the
+// Generator can inspect or adjust it. Since Validate generates the code in
+// order to type-check it, they may be called more than once per file.
+type Hooks struct {
+	// TypeSet is called with every type set before its code is generated,
+	// and can reject it by returning an error.
+	TypeSet func(filename string, typeSet map[string]string) error
+
+	// Output is called with the generated code of every file, and returns
+	// the code to use instead.
+	Output func(filename string, output []byte) ([]byte, error)
+}
+
+// Generator generates type specific code from generic templates.
+type Generator struct {
+	opts Options
+}
+
+// NewGenerator creates a generator with the specified options.
+func NewGenerator(opts Options) *Generator {
+	return &Generator{opts: opts}
+}
+
+// Generate parses the template and generates the code replacing the generic
+// types with the specific types of every type set. If Options.Tests is set,
+// test files (named *_test.go) get a test function for every type set.
+func (g *Generator) Generate(filename string, in io.ReadSeeker, typeSets []map[string]string) ([]byte, error) {
+	return g.generate(filename, in, typeSets, nil)
+}
+
+// GenerateTests generates the test file of the template in filename, such as
+// queue_test.go for queue.go. The template is read along with it, so that
+// the references of the tests to its declarations are resolved. Options.Tests
+// must be set for the tests to be made specific to every type set.
+func (g *Generator) GenerateTests(filename string, in io.ReadSeeker, testFilename string, testIn io.ReadSeeker, typeSets []map[string]string) ([]byte, error) {
+	files, err := readTemplates([]string{filename, testFilename}, []io.ReadSeeker{in, testIn})
+	if err != nil {
+		return nil, err
+	}
+	return g.generate(testFilename, bytes.NewReader(files[1].source), typeSets, files[:1])
+}
+
+// GenerateEach generates the code for every type set of the iterator on
+// its own, as if Generate was called with each of them, and calls emit with
+// the code before generating the next type set, so that only the code of a
+// single type set is held at once. The Origin recorded in the code of each
+// is its type set alone, so that it can be regenerated by itself.
+func (g *Generator) GenerateEach(filename string, in io.ReadSeeker, typeSets *TypeSetIterator, emit func(typeSet map[string]string, output []byte) error) error {
+	each := *g
+	for typeSets.Next() {
+		typeSet := typeSets.TypeSet()
+		if g.opts.Origin != nil {
+			origin := *g.opts.Origin
+			origin.Groups, origin.Types = nil, []string{FormatTypeSet(typeSet)}
+			each.opts.Origin = &origin
+		}
+
+		output, err := each.generate(filename, in, []map[string]string{typeSet}, nil)
+		if err != nil {
+			return err
+		}
+		if err := emit(typeSet, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GeneratePackage generates the code for every file of the template package
+// in dir, so that generic types split across several files can be generated
+// at once. The output is keyed by the base name of each template file, and
+// references between the files are renamed consistently. Test files are only
+// generated if Options.Tests is set.
+func (g *Generator) GeneratePackage(dir string, typeSets []map[string]string) (map[string][]byte, error) {
+	files, err := readPackage(dir, g.opts.Tests)
+	if err != nil {
+		return nil, err
+	}
+	return g.generatePackage(files, typeSets)
+}
+
+// Validate makes sure the specific types satisfy the constraints of the
+// generic types they replace, and type-checks every instantiation if
+// Options.TypeCheck is set. The outFile is the file the code will be written
+// to, in whose package the specific types are resolved.
+func (g *Generator) Validate(filename string, in io.ReadSeeker, typeSets []map[string]string, outFile string) error {
+	if err := checkConstraints(filename, g.opts.PkgName, in, typeSets, g.opts.Imports, outFile); err != nil {
+		return err
+	}
+	if !g.opts.TypeCheck {
+		return nil
+	}
+	return g.typeCheck(filename, in, typeSets, outFile)
+}
+
+// ValidateEach works like Validate for every type set of the iterator on its
+// own, which is written to the file returned by outFile, so that the type sets
+// need not all be held at once. The template is only parsed and type-checked
+// once, and the files are expected to be written to the same package. The
+// validation stops at the first error, including those returned by outFile.
+func (g *Generator) ValidateEach(filename string, in io.ReadSeeker, typeSets *TypeSetIterator, outFile func(typeSet map[string]string) (string, error)) error {
+	c, err := newConstraintChecker(filename, g.opts.PkgName, in, g.opts.Imports)
+	if err != nil {
+		return err
+	}
+
+	var (
+		files     []templateFile
+		tfs       = token.NewFileSet()
+		templates []*ast.File
+	)
+	if g.opts.TypeCheck {
+		if files, err = readTemplates([]string{filename}, []io.ReadSeeker{in}); err != nil {
+			return err
+		}
+		if templates, err = parseTemplates(tfs, files); err != nil {
+			return err
+		}
+	}
+
+	for typeSets.Next() {
+		typeSet := typeSets.TypeSet()
+		out, err := outFile(typeSet)
+		if err != nil {
+			return err
+		}
+		if err := c.check([]map[string]string{typeSet}, out); err != nil {
+			return err
+		}
+		if !g.opts.TypeCheck {
+			continue
+		}
+		checked := out
+		if checked == "" {
+			checked = "genny_check.go"
+		}
+		if err := g.checkTypeSet(files, tfs, templates, []string{checked}, filepath.Dir(out), typeSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTests type-checks the test file of the template in filename if
+// Options.TypeCheck is set, together with the code of the template they test
+// for every type set. The outFile is the file the code of the template is
+// written to, and the tests are written next to it and named after it.
+func (g *Generator) ValidateTests(filename string, in io.ReadSeeker, testFilename string, testIn io.ReadSeeker, typeSets []map[string]string, outFile string) error {
+	if !g.opts.TypeCheck {
+		return nil
+	}
+	files, err := readTemplates([]string{filename, testFilename}, []io.ReadSeeker{in, testIn})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(outFile)
+	if outFile == "" {
+		outFile = "genny_check.go"
+	}
+	outFiles := []string{outFile, strings.TrimSuffix(outFile, ".go") + "_test.go"}
+	return g.checkTemplates(files, outFiles, dir, typeSets)
+}
+
+// ValidatePackage works like Validate for every file of the template package
+// in dir, which is written to outDir. Test files are only type-checked, and
+// only if Options.Tests is set.
+func (g *Generator) ValidatePackage(dir string, typeSets []map[string]string, outDir string) error {
+	if err := g.checkPackageConstraints(dir, typeSets, outDir); err != nil {
+		return err
+	}
+	if !g.opts.TypeCheck {
+		return nil
+	}
+	return g.typeCheckPackage(dir, typeSets, outDir)
+}
+
+// named applies the naming rule to the specific types of a type set.
+func (g *Generator) named(typeSet map[string]string) map[string]string {
+	if g.opts.Naming == nil {
+		return typeSet
+	}
+
+	named := make(map[string]string, len(typeSet))
+	for generic, specific := range typeSet {
+		if _, _, titled := splitTitle(specific); !titled {
+			specific = g.opts.Naming(specific) + ":" + specific
+		}
+		named[generic] = specific
+	}
+	return named
+}
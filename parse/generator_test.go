@@ -0,0 +1,268 @@
+package parse_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratorHeader(t *testing.T) {
+	source := contents("test/queue/generic_queue.go")
+	hash := sha256.Sum256([]byte(source))
+	g := parse.NewGenerator(parse.Options{
+		Header: "Code generated by queuegen from {{.Template}}. DO NOT EDIT.\nTypes: {{.Types}}\nHash: {{.Hash}}",
+	})
+	out, err := g.Generate("generic_queue.go", strings.NewReader(source),
+		[]map[string]string{{"Something": "int"}, {"Something": "string"}})
+	if assert.NoError(t, err) {
+		assert.True(t, strings.HasPrefix(string(out), "// Code generated by queuegen from generic_queue.go. DO NOT EDIT.\n"+
+			"// Types: Something=int; Something=string\n"+
+			"// Hash: "+hex.EncodeToString(hash[:])+"\n\npackage queue"), string(out))
+	}
+
+	// the header must keep marking the code as generated
+	for _, header := range []string{"Generated by queuegen.", "Code generated by {{.Missing}. DO NOT EDIT."} {
+		g = parse.NewGenerator(parse.Options{Header: header})
+		_, err = g.Generate("generic_queue.go", strings.NewReader(source), []map[string]string{{"Something": "int"}})
+		var headerErr *parse.HeaderError
+		assert.True(t, errors.As(err, &headerErr), "%s: %v", header, err)
+	}
+}
+
+func TestGeneratorNaming(t *testing.T) {
+	naming := func(specific string) string {
+		return "My" + strings.Title(specific)
+	}
+
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		g := parse.NewGenerator(parse.Options{Engine: engine, Naming: naming})
+		out, err := g.Generate("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")),
+			[]map[string]string{{"Something": "int"}, {"Something": "Words:[]string"}})
+		if !assert.NoError(t, err, engine.String()) {
+			continue
+		}
+		assert.Contains(t, string(out), "type MyIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []int", engine.String())
+		assert.Contains(t, string(out), "type WordsQueue struct", engine.String())
+		assert.Contains(t, string(out), "items [][]string", engine.String())
+	}
+}
+
+func TestGeneratorHooks(t *testing.T) {
+	var seen []string
+	g := parse.NewGenerator(parse.Options{
+		Engine: parse.EngineTypes,
+		Hooks: parse.Hooks{
+			TypeSet: func(filename string, typeSet map[string]string) error {
+				seen = append(seen, typeSet["Something"])
+				if typeSet["Something"] == "bool" {
+					return errors.New("bool queues are not supported")
+				}
+				return nil
+			},
+			Output: func(filename string, output []byte) ([]byte, error) {
+				return bytes.Replace(output, []byte("Queue"), []byte("Fifo"), -1), nil
+			},
+		},
+	})
+
+	typeSets := []map[string]string{{"Something": "int"}, {"Something": "string"}}
+	out, err := g.Generate("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")), typeSets)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"int", "string"}, seen)
+		assert.Contains(t, string(out), "type IntFifo struct")
+		assert.NotContains(t, string(out), "Queue")
+	}
+
+	_, err = g.Generate("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")),
+		[]map[string]string{{"Something": "bool"}})
+	assert.EqualError(t, err, "bool queues are not supported")
+}
+
+func TestGenericsCompatibility(t *testing.T) {
+	for _, useAst := range []bool{false, true} {
+		out, err := parse.Generics("generic_queue.go", "", strings.NewReader(contents("test/queue/generic_queue.go")),
+			[]map[string]string{{"Something": "int"}}, nil, "", useAst)
+		if assert.NoError(t, err) {
+			assert.Equal(t, contents("test/queue/int_queue.go"), string(out))
+		}
+	}
+}
+
+func TestGeneratorOrigin(t *testing.T) {
+	origin := &parse.Origin{
+		In:      "../queue/generic_queue.go",
+		Groups:  []string{"SMALL=int,string"},
+		Types:   []string{"Something=int", "Something=string"},
+		Pkg:     "gen",
+		Imports: []string{"github.com/kelindar/genny/generic"},
+		Engine:  "types",
+		Header:  "Code generated by \"queuegen\". DO NOT EDIT.\nTypes: {{.Types}}",
+	}
+	g := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, PkgName: "gen", Header: origin.Header, Origin: origin})
+	out, err := g.Generate("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")),
+		[]map[string]string{{"Something": "int"}, {"Something": "string"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(out), "// genny:source -in=\"../queue/generic_queue.go\"\n// genny:source -group=\"SMALL=int,string\"\n// genny:source -types=\"Something=int\"\n// genny:source -types=\"Something=string\"\n")
+	assert.Contains(t, string(out), "\n\npackage gen")
+
+	read, err := parse.ReadOrigin(bytes.NewReader(out))
+	if assert.NoError(t, err) {
+		assert.Equal(t, origin, read)
+	}
+
+	_, err = parse.ReadOrigin(strings.NewReader(contents("test/queue/int_queue.go")))
+	assert.Equal(t, parse.ErrMissingOrigin, err)
+
+	_, err = parse.ReadOrigin(strings.NewReader("// genny:source -out=\"x.go\"\npackage gen\n"))
+	var originErr *parse.OriginError
+	if assert.True(t, errors.As(err, &originErr), "%v", err) {
+		assert.Equal(t, 1, originErr.Line)
+	}
+}
+
+func TestGeneratorTypeLiterals(t *testing.T) {
+	typeSets, err := parse.TypeSet(`Something=map[string]int,func(a, b int) bool,"chan int","chan<- int","<-chan int",struct{ x int },[4]int`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		g := parse.NewGenerator(parse.Options{Engine: engine})
+		out, err := g.Generate("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")), typeSets)
+		if !assert.NoError(t, err, engine.String()) {
+			continue
+		}
+		assert.Contains(t, string(out), "type MapStringIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []map[string]int", engine.String())
+		assert.Contains(t, string(out), "type FuncABIntBoolQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []func(a, b int) bool", engine.String())
+		assert.Contains(t, string(out), "type ChanIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []chan int", engine.String())
+		assert.Contains(t, string(out), "type SendChanIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []chan<- int", engine.String())
+		assert.Contains(t, string(out), "type RecvChanIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items []<-chan int", engine.String())
+		assert.Contains(t, string(out), "type StructXIntQueue struct", engine.String())
+		assert.Contains(t, string(out), "type Array4IntQueue struct", engine.String())
+		assert.Contains(t, string(out), "items [][4]int", engine.String())
+	}
+}
+
+func TestGeneratorGenerateEach(t *testing.T) {
+	origin := &parse.Origin{In: "generic_queue.go", Groups: []string{"SMALL=int,string"}, Types: []string{"Something=SMALL"}}
+	g := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, Origin: origin})
+	it, err := parse.IterateTypeSets("Something=int,string,map[string]int")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var suffixes []string
+	err = g.GenerateEach("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")), it,
+		func(typeSet map[string]string, output []byte) error {
+			suffixes = append(suffixes, parse.FileSuffix(typeSet))
+			assert.Contains(t, string(output), "// genny:source -types=\"Something="+typeSet["Something"]+"\"\n")
+			assert.NotContains(t, string(output), "genny:source -group")
+			assert.Equal(t, 1, strings.Count(string(output), "Queue struct"))
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"int", "string", "mapstringint"}, suffixes)
+	assert.Equal(t, []string{"Something=SMALL"}, origin.Types)
+
+	// generation stops at the first error
+	it.Reset()
+	calls := 0
+	err = g.GenerateEach("generic_queue.go", strings.NewReader(contents("test/queue/generic_queue.go")), it,
+		func(typeSet map[string]string, output []byte) error {
+			calls++
+			return errors.New("disk full")
+		})
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, "float64_int", parse.FileSuffix(map[string]string{"Value": "int", "Key": "float64"}))
+	assert.Equal(t, "words", parse.FileSuffix(map[string]string{"Value": "Words:[]string"}))
+}
+
+func TestGeneratorDeterministic(t *testing.T) {
+	source := contents("test/overlapping/generic_index.go")
+	typeSets := []map[string]string{
+		{"Key": "string", "KeyType": "int", "Item": "float64", "ItemList": "[]float64"},
+		{"Key": "int", "KeyType": "uint8", "Item": "bool", "ItemList": "Flags:[]bool"},
+	}
+
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		g := parse.NewGenerator(parse.Options{Engine: engine})
+		first, err := g.Generate("generic_index.go", strings.NewReader(source), typeSets)
+		if !assert.NoError(t, err, engine.String()) {
+			continue
+		}
+		assert.Contains(t, string(first), "func (i *IntBoolIndex) FlagsOf(k int) []bool", engine.String())
+
+		// the generics overlap, so any other order would change the output
+		for i := 0; i < 100; i++ {
+			out, err := g.Generate("generic_index.go", strings.NewReader(source), typeSets)
+			if !assert.NoError(t, err, engine.String()) || !assert.True(t, bytes.Equal(first, out), "%s: run %d differs", engine, i) {
+				break
+			}
+		}
+	}
+}
+
+func TestGeneratorGenerateTests(t *testing.T) {
+	typeSets := []map[string]string{{"Something": "int"}, {"Something": "string"}}
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		g := parse.NewGenerator(parse.Options{Engine: engine, Tests: true})
+		out, err := g.GenerateTests("generic_queue.go", strings.NewReader(contents("test/tests/generic_queue.go")),
+			"generic_queue_test.go", strings.NewReader(contents("test/tests/generic_queue_tests.go")), typeSets)
+		if assert.NoError(t, err, engine.String()) {
+			assert.Equal(t, contents("test/tests/int_queue_tests.go"), string(out), engine.String())
+		}
+	}
+
+	// the tests are only made specific to the type sets with Options.Tests
+	out, err := parse.NewGenerator(parse.Options{}).Generate("generic_queue_test.go",
+		strings.NewReader(contents("test/tests/generic_queue_tests.go")), typeSets[:1])
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(out), "func TestNew(t *testing.T)")
+		assert.Contains(t, string(out), "func newQueue(t *testing.T) *IntQueue")
+	}
+}
+
+func TestGeneratorTypesKeepsNames(t *testing.T) {
+	template := `package cache
+
+import "github.com/kelindar/genny/generic"
+
+type Key generic.Type
+
+type KeyCache struct {
+	Key Key
+}
+
+func (c *KeyCache) Keys() []Key {
+	keys := []Key{c.Key}
+	return append(keys, keyDefaults...)
+}
+`
+	g := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes})
+	out, err := g.Generate("generic_cache.go", strings.NewReader(template), []map[string]string{{"Key": "string"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(out), "type StringCache struct {\n\tKey string\n}")
+	assert.Contains(t, string(out), "func (c *StringCache) Strings() []string {")
+	assert.Contains(t, string(out), "keys := []string{c.Key}")
+
+	// unresolved identifiers could refer to anything, so they are left alone
+	assert.Contains(t, string(out), "append(keys, keyDefaults...)")
+}
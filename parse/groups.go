@@ -0,0 +1,87 @@
+package parse
+
+// The names of the predefined groups.
+const (
+	integers    = "INTEGERS"
+	signed      = "SIGNED"
+	unsigned    = "UNSIGNED"
+	floats      = "FLOATS"
+	ordered     = "ORDERED"
+	comparables = "COMPARABLE"
+)
+
+// Groups are named groups of specific types, which can be used wherever a
+// specific type can, such as "Key=KEYS" for the group KEYS. The predefined
+// groups BUILTINS, NUMBERS, INTEGERS, SIGNED, UNSIGNED, FLOATS, ORDERED and
+// COMPARABLE are always available, unless a group of the same name replaces
+// them.
+type Groups map[string][]string
+
+// NewGroups gets the groups with the definitions, such as "KEYS=int,string".
+// Every definition may use the groups defined before it, and the same syntax
+// as the specific types of a generic, such as "KEYS=ORDERED-float32,float64".
+func NewGroups(definitions ...string) (Groups, error) {
+	groups := make(Groups)
+	for _, definition := range definitions {
+		if err := groups.Define(definition); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// Define adds (or replaces) the group with the definition, such as
+// "KEYS=int,string".
+func (g Groups) Define(definition string) error {
+	tokens, err := tokenizeTypes(definition)
+	if err != nil {
+		return err
+	}
+
+	generics, i := readGenerics(tokens, 0)
+	if len(generics) != 1 {
+		return &TypeArgsError{Arg: definition, Message: "Group=Specific expected"}
+	}
+	specifics, i, err := g.readSpecifics(definition, tokens, i, true)
+	if err != nil {
+		return err
+	}
+	if i < len(tokens) {
+		return &TypeArgsError{Arg: definition, Message: "Group=Specific expected"}
+	}
+
+	g[generics[0]] = specifics
+	return nil
+}
+
+// lookup gets the specific types of the group, if there is one.
+func (g Groups) lookup(name string) ([]string, bool) {
+	if specifics, ok := g[name]; ok {
+		return specifics, true
+	}
+	return predefinedGroup(name)
+}
+
+// predefinedGroup gets the specific types of the predefined group, if there
+// is one.
+func predefinedGroup(name string) ([]string, bool) {
+	switch name {
+	case builtins:
+		return Builtins, true
+	case numbers:
+		return Numbers, true
+	case integers:
+		return Integers, true
+	case signed:
+		return Signed, true
+	case unsigned:
+		return Unsigned, true
+	case floats:
+		return Floats, true
+	case ordered:
+		return Ordered, true
+	case comparables:
+		return Comparable, true
+	}
+	return nil, false
+}
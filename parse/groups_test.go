@@ -0,0 +1,73 @@
+package parse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredefinedGroups(t *testing.T) {
+	for arg, expected := range map[string][]string{
+		"Key=INTEGERS":                 parse.Integers,
+		"Key=SIGNED,UNSIGNED":          parse.Integers,
+		"Key=FLOATS":                   {"float32", "float64"},
+		"Key=ORDERED-INTEGERS,FLOATS":  {"string"},
+		"Key=COMPARABLE&SIGNED":        parse.Signed,
+		"Key=NUMBERS-INTEGERS":         parse.Floats,
+		"Key=COMPARABLE-NUMBERS,error": {"bool", "byte", "complex128", "complex64", "rune", "string", "uintptr"},
+	} {
+		ts, err := parse.TypeSet(arg)
+		if !assert.NoError(t, err, arg) {
+			continue
+		}
+		var actual []string
+		for _, typeSet := range ts {
+			actual = append(actual, typeSet["Key"])
+		}
+		assert.ElementsMatch(t, expected, actual, arg)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	groups, err := parse.NewGroups("KEYS=int,string", "VALUES = KEYS,[]byte,Things:[]Thing", "FLOATS=float64", "SMALL=VALUES-string")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, parse.Groups{
+		"KEYS":   {"int", "string"},
+		"VALUES": {"int", "string", "[]byte", "Things:[]Thing"},
+		"FLOATS": {"float64"},
+		"SMALL":  {"int", "[]byte", "Things:[]Thing"},
+	}, groups)
+
+	ts, err := groups.TypeSets("Key=KEYS Value=FLOATS", "Key,Value=SMALL-int:bool")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Key": "int", "Value": "float64"},
+			{"Key": "string", "Value": "float64"},
+			{"Key": "[]byte", "Value": "bool"},
+			{"Key": "Things:[]Thing", "Value": "bool"},
+		}, ts)
+	}
+
+	// the TypeSet function takes unknown groups for types
+	ts, err = parse.TypeSet("Key=KEYS")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{{"Key": "KEYS"}}, ts)
+	}
+
+	for _, definition := range []string{
+		"KEYS",
+		"KEYS=",
+		"KEYS=int Other=string",
+		"KEYS,VALUES=int:string",
+		"KEYS=int-int",
+		"KEYS=int;string",
+	} {
+		err := groups.Define(definition)
+		var argsErr *parse.TypeArgsError
+		assert.True(t, errors.As(err, &argsErr), "%s: %v", definition, err)
+	}
+}
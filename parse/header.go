@@ -0,0 +1,111 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	buildinfo "runtime/debug"
+	"strings"
+	"text/template"
+)
+
+// modulePath is the path of the genny module, whose version is reported in
+// the header of the generated code.
+const modulePath = "github.com/kelindar/genny"
+
+// DefaultHeader is the header template of the generated code, unless
+// Options.Header is specified.
+const DefaultHeader = `Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+Any changes will be lost if this file is regenerated.{{if .TemplateVersion}}
+Template: {{.Template}}@{{.TemplateVersion}}{{end}}`
+
+// generatedLine matches the line which marks a file as generated, so that
+// linters and other tools skip it.
+var generatedLine = regexp.MustCompile(`^Code generated .* DO NOT EDIT\.$`)
+
+// HeaderData is the data available to the header template.
+type HeaderData struct {
+	// Template is the name of the template file, and TemplateVersion is its
+	// version, if it was resolved from a versioned source such as a module.
+	Template        string
+	TemplateVersion string
+
+	// TypeSets are the type sets the code is generated for, and Types is
+	// their textual form, such as "Key=string Value=int; Key=int Value=int".
+	TypeSets []map[string]string
+	Types    string
+
+	// Version is the version of genny generating the code.
+	Version string
+
+	// Hash is the SHA-256 hash of the template source, in hex.
+	Hash string
+}
+
+// header renders the header template for the template file and the type
+// sets, as a comment written at the top of the generated code.
+func (g *Generator) header(filename string, source []byte, typeSets []map[string]string) (string, error) {
+	text := g.opts.Header
+	if text == "" {
+		text = DefaultHeader
+	}
+
+	tmpl, err := template.New("header").Parse(text)
+	if err != nil {
+		return "", &HeaderError{Header: text, Err: err}
+	}
+
+	types := make([]string, len(typeSets))
+	for i, typeSet := range typeSets {
+		types[i] = FormatTypeSet(typeSet)
+	}
+	hash := sha256.Sum256(source)
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, HeaderData{
+		Template:        filename,
+		TemplateVersion: g.opts.TemplateVersion,
+		TypeSets:        typeSets,
+		Types:           strings.Join(types, "; "),
+		Version:         version(),
+		Hash:            hex.EncodeToString(hash[:]),
+	}); err != nil {
+		return "", &HeaderError{Header: text, Err: err}
+	}
+
+	lines := strings.Split(strings.TrimRight(rendered.String(), "\n"), "\n")
+	marked := false
+	for _, line := range lines {
+		marked = marked || generatedLine.MatchString(line)
+	}
+	if !marked {
+		return "", &HeaderError{Header: text}
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	b.WriteString("\n\n")
+	return b.String(), nil
+}
+
+// version gets the version of genny from the build information of the
+// running binary, which is "(devel)" unless genny was built as a dependency
+// or installed with a version.
+func version() string {
+	info, ok := buildinfo.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
@@ -0,0 +1,103 @@
+package parse
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+)
+
+// genericSource mirrors the marker types of the generic package, so that
+// templates can be type-checked without locating genny on disk.
+const genericSource = `package generic
+
+type Type interface{}
+
+type Number float64
+
+type Comparable interface{}
+
+type Ordered float64
+
+type Integer int
+
+type Float float64
+`
+
+// isGenericImport returns whether the import path refers to the generic
+// package of genny (or one of its forks).
+func isGenericImport(path string) bool {
+	return strings.HasSuffix(path, "genny/generic")
+}
+
+// typesImporter resolves the imports of the code being type-checked. The
+// generic package is synthesized from genericSource, other packages are read
+// from export data where available and type-checked from source otherwise.
+type typesImporter struct {
+	lock     sync.Mutex
+	fset     *token.FileSet
+	packages map[string]*types.Package
+	compiled types.Importer
+	source   types.ImporterFrom
+}
+
+// sharedImporter is reused between calls, since loading the standard library
+// is considerably more expensive than type-checking a template.
+var sharedImporter = newTypesImporter()
+
+func newTypesImporter() *typesImporter {
+	fset := token.NewFileSet()
+	return &typesImporter{
+		fset:     fset,
+		packages: make(map[string]*types.Package),
+		compiled: importer.Default(),
+		source:   importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+	}
+}
+
+// Import imports the package with the specified path.
+func (imp *typesImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+// ImportFrom imports the package with the specified path, resolving it
+// relative to the directory dir.
+func (imp *typesImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	imp.lock.Lock()
+	defer imp.lock.Unlock()
+
+	key := path
+	if strings.HasPrefix(path, ".") {
+		key = dir + "|" + path
+	}
+	if pkg, ok := imp.packages[key]; ok {
+		return pkg, nil
+	}
+
+	var pkg *types.Package
+	var err error
+	if isGenericImport(path) {
+		pkg, err = imp.generic(path)
+	} else if pkg, err = imp.compiled.Import(path); err != nil || !pkg.Complete() {
+		pkg, err = imp.source.ImportFrom(path, dir, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	imp.packages[key] = pkg
+	return pkg, nil
+}
+
+// generic type-checks genericSource as the package with the specified path.
+func (imp *typesImporter) generic(path string) (*types.Package, error) {
+	file, err := parser.ParseFile(imp.fset, "generic.go", genericSource, 0)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: imp}
+	return conf.Check(path, imp.fset, []*ast.File{file}, nil)
+}
@@ -0,0 +1,126 @@
+package parse
+
+import "strconv"
+
+// TypeSetIterator expands type strings into their type sets one at a time,
+// in the same order as TypeSets, so that the combinations of large type
+// strings such as "Key=BUILTINS Value=BUILTINS" are never held at once.
+//
+//	it, err := parse.IterateTypeSets("Key=BUILTINS Value=BUILTINS")
+//	if err != nil {
+//		return err
+//	}
+//	for it.Next() {
+//		typeSet := it.TypeSet()
+//		...
+//	}
+type TypeSetIterator struct {
+	expansions []*expansion
+	current    int   // the index of the expansion being iterated
+	cursors    []int // the index of the type set of every group, or nil
+	typeSet    map[string]string
+}
+
+// IterateTypeSets gets an iterator over the type sets of the type strings.
+// Unlike TypeSets, a type string whose combinations are all dropped (such as
+// "Key=int Value=int Key!=Value") is not an error, but has no type sets.
+func IterateTypeSets(args ...string) (*TypeSetIterator, error) {
+	return Groups(nil).IterateTypeSets(args...)
+}
+
+// IterateTypeSets gets an iterator over the type sets of the type strings
+// like the IterateTypeSets function, with the groups in addition to the
+// predefined ones.
+func (g Groups) IterateTypeSets(args ...string) (*TypeSetIterator, error) {
+	if len(args) == 0 {
+		return nil, &TypeArgsError{Message: "Generic=Specific expected"}
+	}
+
+	it := &TypeSetIterator{}
+	for _, arg := range args {
+		e, err := g.expansion(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(it.expansions) > 0 && !sameGenerics(it.expansions[0].generics, e.generics) {
+			return nil, &TypeArgsError{Arg: arg, Message: "the generics differ from those of " + strconv.Quote(args[0])}
+		}
+		it.expansions = append(it.expansions, e)
+	}
+	return it, nil
+}
+
+// Next advances the iterator to the next type set, and gets whether there
+// is one.
+func (it *TypeSetIterator) Next() bool {
+	for it.current < len(it.expansions) {
+		e := it.expansions[it.current]
+		if it.cursors == nil {
+			it.cursors = make([]int, len(e.groups))
+		} else if !e.advance(it.cursors) {
+			it.current++
+			it.cursors = nil
+			continue
+		}
+
+		if typeSet := e.typeSet(it.cursors); isDistinctTypeSet(typeSet, e.distinct) {
+			it.typeSet = typeSet
+			return true
+		}
+	}
+
+	it.typeSet = nil
+	return false
+}
+
+// TypeSet gets the current type set, which belongs to the caller.
+func (it *TypeSetIterator) TypeSet() map[string]string {
+	return it.typeSet
+}
+
+// Reset rewinds the iterator to before the first type set.
+func (it *TypeSetIterator) Reset() {
+	it.current, it.cursors, it.typeSet = 0, nil, nil
+}
+
+// expansion is a parsed type string, whose type sets are the combinations of
+// a type set of every group which satisfy the distinct predicates.
+type expansion struct {
+	generics map[string]string // the generics, as keys
+	groups   [][]map[string]string
+	distinct [][2]string // the generics which must differ
+}
+
+// advance moves the cursors to the next combination, the type sets of the
+// last group varying the fastest, and gets whether there is one.
+func (e *expansion) advance(cursors []int) bool {
+	for i := len(cursors) - 1; i >= 0; i-- {
+		if cursors[i]++; cursors[i] < len(e.groups[i]) {
+			return true
+		}
+		cursors[i] = 0
+	}
+	return false
+}
+
+// typeSet gets the type set of the combination at the cursors.
+func (e *expansion) typeSet(cursors []int) map[string]string {
+	typeSet := make(map[string]string, len(e.generics))
+	for i, cursor := range cursors {
+		for generic, specific := range e.groups[i][cursor] {
+			typeSet[generic] = specific
+		}
+	}
+	return typeSet
+}
+
+// typeSets gets every type set of the expansion.
+func (e *expansion) typeSets() []map[string]string {
+	it := &TypeSetIterator{expansions: []*expansion{e}}
+
+	var typeSets []map[string]string
+	for it.Next() {
+		typeSets = append(typeSets, it.TypeSet())
+	}
+	return typeSets
+}
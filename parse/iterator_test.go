@@ -0,0 +1,43 @@
+package parse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeSetIterator(t *testing.T) {
+	args := []string{"Key=BUILTINS Value=BUILTINS Key!=Value", "Key,Value=int:int", "Key=int Value=int Key!=Value"}
+	expected, err := parse.TypeSets(args[:2]...)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	it, err := parse.IterateTypeSets(args...)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for pass := 0; pass < 2; pass++ {
+		var actual []map[string]string
+		for it.Next() {
+			actual = append(actual, it.TypeSet())
+		}
+		assert.Equal(t, len(parse.Builtins)*(len(parse.Builtins)-1)-4+1, len(actual))
+		assert.Equal(t, expected, actual)
+		assert.False(t, it.Next())
+		assert.Nil(t, it.TypeSet())
+		it.Reset()
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"Key"},
+		{"Key=int", "Value=int"},
+	} {
+		_, err := parse.IterateTypeSets(args...)
+		var argsErr *parse.TypeArgsError
+		assert.True(t, errors.As(err, &argsErr), "%v: %v", args, err)
+	}
+}
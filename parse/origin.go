@@ -0,0 +1,122 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// originPrefix starts every line of the block recording the origin of the
+// generated code.
+const originPrefix = "// genny:source "
+
+// Origin records the arguments which generated a file, so that it can be
+// regenerated without knowing the command which produced it. It is written
+// to the generated code as a block of "// genny:source" lines, one for every
+// flag of the command, such as
+//
+//	// genny:source -in="generic_queue.go"
+//	// genny:source -types="Something=int,string"
+//
+// In is relative to the directory of the generated file, Groups has the
+// definition of every group, and Types has an entry for every type string
+// given to the command.
+type Origin struct {
+	In      string
+	Groups  []string
+	Types   []string
+	Pkg     string
+	Imports []string
+	Tag     string
+	Engine  string
+	Header  string
+}
+
+// String gets the block recording the origin.
+func (o Origin) String() string {
+	var b strings.Builder
+	line := func(flag, value string) {
+		if value != "" {
+			b.WriteString(originPrefix + "-" + flag + "=" + strconv.Quote(value) + "\n")
+		}
+	}
+
+	line("in", o.In)
+	for _, group := range o.Groups {
+		line("group", group)
+	}
+	for _, types := range o.Types {
+		line("types", types)
+	}
+	line("pkg", o.Pkg)
+	for _, imp := range o.Imports {
+		line("imp", imp)
+	}
+	line("tag", o.Tag)
+	line("engine", o.Engine)
+	line("header", o.Header)
+	return b.String()
+}
+
+// ReadOrigin reads the origin recorded in the generated code, which precedes
+// the package clause.
+func ReadOrigin(in io.Reader) (*Origin, error) {
+	var (
+		origin  Origin
+		found   bool
+		lineNum int
+	)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		lineNum++
+		if bytes.HasPrefix(scanner.Bytes(), packageKeyword) {
+			break
+		}
+		if !strings.HasPrefix(scanner.Text(), originPrefix) {
+			continue
+		}
+
+		text := strings.TrimPrefix(scanner.Text(), originPrefix)
+		sep := strings.Index(text, "=")
+		if !strings.HasPrefix(text, "-") || sep < 0 {
+			return nil, &OriginError{Line: lineNum, Text: scanner.Text()}
+		}
+		value, err := strconv.Unquote(text[sep+1:])
+		if err != nil {
+			return nil, &OriginError{Line: lineNum, Text: scanner.Text()}
+		}
+
+		switch text[1:sep] {
+		case "in":
+			origin.In = value
+		case "group":
+			origin.Groups = append(origin.Groups, value)
+		case "types":
+			origin.Types = append(origin.Types, value)
+		case "pkg":
+			origin.Pkg = value
+		case "imp":
+			origin.Imports = append(origin.Imports, value)
+		case "tag":
+			origin.Tag = value
+		case "engine":
+			origin.Engine = value
+		case "header":
+			origin.Header = value
+		default:
+			return nil, &OriginError{Line: lineNum, Text: scanner.Text()}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrMissingOrigin
+	}
+	return &origin, nil
+}
@@ -0,0 +1,147 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// templateFile is a file of a template package.
+type templateFile struct {
+	name   string
+	source []byte
+}
+
+// generatePackage generates the specific code for the files of a template
+// package, keyed by their base names.
+func (g *Generator) generatePackage(files []templateFile, typeSets []map[string]string) (map[string][]byte, error) {
+
+	// the marker types may be declared in any file of the package
+	if err := checkMarkers(packageMarkers(files), typeSets); err != nil {
+		return nil, err
+	}
+
+	outputs := make(map[string][]byte, len(files))
+	for i, file := range files {
+		output, err := g.generate(file.name, bytes.NewReader(file.source), typeSets, siblings(files, i))
+		if err != nil {
+			return nil, err
+		}
+		outputs[filepath.Base(file.name)] = output
+	}
+	return outputs, nil
+}
+
+// typeCheckPackage works like typeCheck for a template package. Every type
+// set is generated for all the files of the package in dir, which are then
+// type-checked together with the other files of the package in outDir.
+func (g *Generator) typeCheckPackage(dir string, typeSets []map[string]string, outDir string) error {
+	files, err := readPackage(dir, g.opts.Tests)
+	if err != nil {
+		return err
+	}
+
+	outFiles := make([]string, len(files))
+	for i, file := range files {
+		outFiles[i] = filepath.Join(outDir, filepath.Base(file.name))
+	}
+	return g.checkTemplates(files, outFiles, outDir, typeSets)
+}
+
+// checkPackageConstraints works like checkConstraints for every file of the
+// template package in dir, resolving the specific types in outDir.
+func (g *Generator) checkPackageConstraints(dir string, typeSets []map[string]string, outDir string) error {
+	files, err := readPackage(dir, false)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		outFile := filepath.Join(outDir, filepath.Base(file.name))
+		if err := checkConstraints(file.name, g.opts.PkgName, bytes.NewReader(file.source), typeSets, g.opts.Imports, outFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readPackage reads the Go files of the template package in dir, sorted by
+// name, including the test files if tests is set.
+func readPackage(dir string, tests bool) ([]templateFile, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+
+	var files []templateFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || (!tests && isTestFile(name)) {
+			continue
+		}
+		source, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, sourceError(err)
+		}
+		files = append(files, templateFile{name: filepath.Join(dir, name), source: source})
+	}
+	if len(files) == 0 {
+		return nil, &NoTemplateFilesError{Dir: dir}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// readTemplates reads the template files from their readers.
+func readTemplates(filenames []string, ins []io.ReadSeeker) ([]templateFile, error) {
+	files := make([]templateFile, len(filenames))
+	for i, in := range ins {
+		in.Seek(0, os.SEEK_SET)
+		source, err := ioutil.ReadAll(in)
+		if err != nil {
+			return nil, sourceError(err)
+		}
+		files[i] = templateFile{name: filenames[i], source: source}
+	}
+	return files, nil
+}
+
+// siblings gets the files of the package other than the i-th one.
+func siblings(files []templateFile, i int) []templateFile {
+	others := make([]templateFile, 0, len(files)-1)
+	others = append(others, files[:i]...)
+	return append(others, files[i+1:]...)
+}
+
+// packageMarkers gets the marker types of the generic types declared in any of
+// the files. Files which cannot be parsed are skipped, since generating them
+// reports the error.
+func packageMarkers(files []templateFile) map[string]marker {
+	fs := token.NewFileSet()
+	markers := make(map[string]marker)
+	for _, file := range files {
+		parsed, err := parser.ParseFile(fs, file.name, file.source, 0)
+		if err != nil {
+			continue
+		}
+		for generic, m := range genericMarkers(fs, parsed) {
+			markers[generic] = m
+		}
+	}
+	return markers
+}
+
+// parseTemplate parses a template file in order to map errors back to it.
+func parseTemplate(fs *token.FileSet, file templateFile) (*ast.File, error) {
+	parsed, err := parser.ParseFile(fs, file.name, file.source, 0)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,40 @@
+package parse_test
+
+import (
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackage(t *testing.T) {
+	typeSets := []map[string]string{{"Item": "int"}, {"Item": "string"}}
+	for _, engine := range []parse.Engine{parse.EngineLegacy, parse.EngineAst, parse.EngineTypes} {
+		outputs, err := parse.NewGenerator(parse.Options{Engine: engine}).GeneratePackage("test/package", typeSets)
+		if !assert.NoError(t, err, engine.String()) {
+			continue
+		}
+		assert.Len(t, outputs, 2, engine.String())
+		assert.Equal(t, contents("test/package/expected/tree.go"), string(outputs["tree.go"]), engine.String())
+		assert.Equal(t, contents("test/package/expected/iter.go"), string(outputs["iter.go"]), engine.String())
+
+		err = parse.NewGenerator(parse.Options{Engine: engine, TypeCheck: true}).ValidatePackage("test/package", typeSets, "test/package/expected")
+		assert.NoError(t, err, engine.String())
+
+		err = parse.NewGenerator(parse.Options{Engine: engine, TypeCheck: true}).ValidatePackage("test/package", []map[string]string{{"Item": "Missing"}}, "test/package/expected")
+		if assert.Error(t, err, engine.String()) {
+			assert.Contains(t, err.Error(), "test/package/tree.go:")
+			assert.Contains(t, err.Error(), "Item=Missing")
+		}
+	}
+
+	outputs, err := parse.NewGenerator(parse.Options{Engine: parse.EngineTypes, Tests: true}).GeneratePackage("test/package", typeSets)
+	if assert.NoError(t, err) {
+		assert.Len(t, outputs, 3)
+		assert.Contains(t, string(outputs["tree_test.go"]), "func TestIntTree(t *testing.T)")
+		assert.Contains(t, string(outputs["tree_test.go"]), "func TestEmptyString(t *testing.T)")
+	}
+
+	_, err = parse.NewGenerator(parse.Options{Engine: parse.EngineTypes}).GeneratePackage("test", typeSets)
+	assert.Error(t, err)
+}
@@ -10,27 +10,61 @@ import (
 	"go/scanner"
 	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/imports"
 )
 
-var header = `
-// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
-// Any changes will be lost if this file is regenerated.
-
+const (
+	debug = false
+)
 
-`
+// Engine selects the implementation used to substitute specific types into
+// the generic source.
+type Engine int
 
 const (
-	debug = false
+	// EngineLegacy rewrites the source line by line using the Go scanner.
+	EngineLegacy Engine = iota
+	// EngineAst rewrites the syntax tree based on identifier names.
+	EngineAst
+	// EngineTypes type-checks the source and only rewrites identifiers which
+	// resolve to the generic type declarations or are derived from them.
+	EngineTypes
 )
 
+var engineNames = map[Engine]string{
+	EngineLegacy: "legacy",
+	EngineAst:    "ast",
+	EngineTypes:  "types",
+}
+
+// String gets the name of the engine, as accepted by ParseEngine.
+func (e Engine) String() string {
+	if name, ok := engineNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("Engine(%d)", int(e))
+}
+
+// ParseEngine gets the engine with the specified name.
+func ParseEngine(name string) (Engine, error) {
+	for engine, n := range engineNames {
+		if strings.EqualFold(n, name) {
+			return engine, nil
+		}
+	}
+	return EngineLegacy, &EngineError{Name: name}
+}
+
 var (
 	packageKeyword = []byte("package")
 	importKeyword  = []byte("import")
@@ -39,6 +73,7 @@ var (
 	genericPackage = "generic"
 	genericType    = "generic.Type"
 	genericNumber  = "generic.Number"
+	markerTypes    = []string{"Type", "Number", "Comparable", "Ordered", "Integer", "Float"}
 	linefeed       = "\r\n"
 )
 var unwantedLinePrefixes = [][]byte{
@@ -49,7 +84,7 @@ var unwantedLinePrefixes = [][]byte{
 func subIntoLiteral(prefix, lit, typeTemplate, specificType string) string {
 	// print("l >> %s ... tt >> %s", lit, typeTemplate)
 	if lit == typeTemplate {
-		return specificType
+		return typify(specificType)
 	}
 
 	if !containsFold(lit, typeTemplate) {
@@ -69,7 +104,7 @@ func subIntoLiteral(prefix, lit, typeTemplate, specificType string) string {
 
 	// result := lit //replaceBoundary(lit, typeTemplate, specificType)
 	typeregex := regexp.MustCompile("\\b" + typeTemplate + "\\b")
-	result := typeregex.ReplaceAllString(lit, specificType)
+	result := typeregex.ReplaceAllString(lit, typify(specificType))
 	result = strings.Replace(result, typeTemplate, replacer, -1)
 
 	if strings.HasPrefix(result, specificLg) && !isExported(lit) {
@@ -142,7 +177,7 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 	fs := token.NewFileSet()
 	file, err := parser.ParseFile(fs, filename, in, 0)
 	if err != nil {
-		return nil, &errSource{Err: err}
+		return nil, sourceError(err)
 	}
 
 	// make sure every generic.Type is represented in the types
@@ -160,7 +195,7 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 					if name, ok := tt.X.(*ast.Ident); ok {
 						if name.Name == genericPackage {
 							if _, ok := typeSet[ts.Name.Name]; !ok {
-								return nil, &errMissingSpecificType{GenericType: ts.Name.Name}
+								return nil, &MissingSpecificTypeError{GenericType: ts.Name.Name, Pos: fs.Position(ts.Pos()), TypeSet: typeSet}
 							}
 						}
 					}
@@ -179,6 +214,7 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 	reInterfaceEnd := regexp.MustCompile(`^\s*\}`)
 	var interfaceLines []string
 	interfaceContainsType := false
+	order := substitutionOrder(typeSet)
 	for scanner.Scan() {
 
 		line := scanner.Text()
@@ -198,7 +234,7 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 		}
 
 		// does this line contain generic.Type?
-		if strings.Contains(line, genericType) || strings.Contains(line, genericNumber) {
+		if containsMarker(line) {
 			comment = ""
 			if len(interfaceLines) > 0 {
 				interfaceContainsType = true
@@ -206,9 +242,9 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 			continue
 		}
 
-		for t, specificType := range typeSet {
+		for _, t := range order {
 			if containsFold(line, t) {
-				newLine := subTypeIntoLine(line, t, specificType)
+				newLine := subTypeIntoLine(line, t, typeSet[t])
 				line = newLine
 			}
 		}
@@ -245,26 +281,90 @@ func generateSpecific(filename string, in io.ReadSeeker, typeSet map[string]stri
 }
 
 // Generics parses the source file and generates the bytes replacing the
-// generic types for the keys map with the specific types (its value).
+// generic types for the keys map with the specific types (its value). Test
+// files (named *_test.go) get a test function for every type set. See
+// Generator for more options.
 func Generics(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]string, importPaths []string, stripTag string, useAstImpl bool) ([]byte, error) {
+	engine := EngineLegacy
+	if useAstImpl {
+		engine = EngineAst
+	}
+	return NewGenerator(Options{
+		Engine:   engine,
+		PkgName:  pkgName,
+		Imports:  importPaths,
+		StripTag: stripTag,
+	}).Generate(filename, in, typeSets)
+}
+
+// generate generates the specific code for a template file. The siblings are
+// the other files of the template package, which the types engine needs in
+// order to resolve references to declarations in those files.
+func (g *Generator) generate(filename string, in io.ReadSeeker, typeSets []map[string]string, siblings []templateFile) ([]byte, error) {
+	// the header records the template itself, rather than the prepared tests
+	in.Seek(0, os.SEEK_SET)
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+	header, err := g.header(filename, source, typeSets)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName := g.opts.PkgName
+	prepared := g.opts.Tests && isTestFile(filename)
+
 	localUnwantedLinePrefixes := [][]byte{}
 	localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, unwantedLinePrefixes...)
 
-	if stripTag != "" {
-		localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, []byte(fmt.Sprintf("// +build %s", stripTag)))
+	if g.opts.StripTag != "" {
+		localUnwantedLinePrefixes = append(localUnwantedLinePrefixes, []byte(fmt.Sprintf("// +build %s", g.opts.StripTag)))
+	}
+
+	// reject specific types which cannot satisfy the marker types
+	in.Seek(0, os.SEEK_SET)
+	fs := token.NewFileSet()
+	template, err := parser.ParseFile(fs, filename, in, 0)
+	if err == nil {
+		if err := checkMarkers(genericMarkers(fs, template), typeSets); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil || prepared {
+		// prepared test files do not line up with the template
+		template = nil
 	}
 
 	totalOutput := [][]byte{}
 
 	for _, typeSet := range typeSets {
+		if g.opts.Hooks.TypeSet != nil {
+			if err := g.opts.Hooks.TypeSet(filename, typeSet); err != nil {
+				return nil, err
+			}
+		}
+		typeSet = g.named(typeSet)
+
+		// test files are prepared for every type set
+		typeIn := in
+		if prepared {
+			var err error
+			if typeIn, pkgName, err = prepareTestFile(filename, g.opts.PkgName, in, typeSet); err != nil {
+				return nil, err
+			}
+		}
 
 		// generate the specifics
 		var parsed []byte
 		var err error
-		if useAstImpl {
-			parsed, err = generateSpecificAst(filename, in, typeSet)
-		} else {
-			parsed, err = generateSpecific(filename, in, typeSet)
+		switch g.opts.Engine {
+		case EngineAst:
+			parsed, err = generateSpecificAst(filename, typeIn, typeSet)
+		case EngineTypes:
+			parsed, err = generateSpecificTyped(filename, typeIn, typeSet, siblings)
+		default:
+			parsed, err = generateSpecific(filename, typeIn, typeSet)
 		}
 		if err != nil {
 			return nil, err
@@ -283,6 +383,9 @@ func Generics(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]
 	importLineIndex := -1
 	var collectedImports stringArraySet
 	cleanOutputLines := []string{header}
+	if g.opts.Origin != nil {
+		cleanOutputLines = append(cleanOutputLines, g.opts.Origin.String()+"\n")
+	}
 	for fileIndex, transformedOutput := range totalOutput {
 		insideImportBlock := false
 		packageFoundForFile := false
@@ -353,12 +456,16 @@ func Generics(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]
 		}
 	}
 
-	var linesWithImport []string
-	linesWithImport = append(linesWithImport, cleanOutputLines[:importLineIndex]...)
-	linesWithImport = append(linesWithImport, fmt.Sprintln("import ("))
-	linesWithImport = append(linesWithImport, collectedImports...)
-	linesWithImport = append(linesWithImport, fmt.Sprintln(")"))
-	linesWithImport = append(linesWithImport, cleanOutputLines[importLineIndex+1:]...)
+	// files without any import have no import block to fill in
+	linesWithImport := cleanOutputLines
+	if importLineIndex >= 0 {
+		linesWithImport = nil
+		linesWithImport = append(linesWithImport, cleanOutputLines[:importLineIndex]...)
+		linesWithImport = append(linesWithImport, fmt.Sprintln("import ("))
+		linesWithImport = append(linesWithImport, collectedImports...)
+		linesWithImport = append(linesWithImport, fmt.Sprintln(")"))
+		linesWithImport = append(linesWithImport, cleanOutputLines[importLineIndex+1:]...)
+	}
 
 	cleanOutput := strings.Join(linesWithImport, "")
 
@@ -368,16 +475,18 @@ func Generics(filename, pkgName string, in io.ReadSeeker, typeSets []map[string]
 	if pkgName != "" {
 		output = changePackage(bytes.NewReader([]byte(output)), pkgName)
 	}
-	if len(importPaths) > 0 {
-		output = addImports(bytes.NewReader(output), importPaths)
+	if len(g.opts.Imports) > 0 {
+		output = addImports(bytes.NewReader(output), g.opts.Imports)
 	}
 	// fix the imports
-	var err error
 	output, err = imports.Process(filename, output, nil)
 	if err != nil {
-		return nil, &errImports{Err: err}
+		return nil, importsError(err, fs, template, totalOutput, typeSets)
 	}
 
+	if g.opts.Hooks.Output != nil {
+		return g.opts.Hooks.Output(filename, output)
+	}
 	return output, nil
 }
 
@@ -394,12 +503,15 @@ func isAlphaNumeric(r rune) bool {
 // names etc.
 // If s matches format `<Title>:<Type>` then <Title> is returned
 func wordify(s string, exported bool) string {
-	if sepIdx := strings.Index(s, ":"); sepIdx >= 0 {
-		s = s[:sepIdx]
+	if title, _, ok := splitTitle(s); ok {
+		s = title
 	} else {
 		s = strings.TrimRight(s, "{}")
 		s = strings.TrimLeft(s, "*&")
 		s = strings.Replace(s, ".", "", -1)
+		if !isIdentifier(s) {
+			s = identify(s)
+		}
 	}
 	if !exported {
 		return strings.ToLower(string(s[0])) + s[1:]
@@ -408,13 +520,97 @@ func wordify(s string, exported bool) string {
 	return strings.ToUpper(string(s[0])) + s[1:]
 }
 
+// identify turns a type which cannot be used in identifiers, such as
+// map[string]int, into a word made of its identifiers, such as mapStringInt.
+// Slices and arrays become "slice" and "array", and the direction of channels
+// is kept apart with "send" and "recv", such as sendChanInt for chan<- int.
+func identify(s string) string {
+	var b strings.Builder
+	word := func(w string) {
+		if b.Len() > 0 {
+			w = strings.ToUpper(string(w[0])) + w[1:]
+		}
+		b.WriteString(w)
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case isAlphaNumeric(r):
+			j := i + size
+			for j < len(s) {
+				r, size = utf8.DecodeRuneInString(s[j:])
+				if !isAlphaNumeric(r) {
+					break
+				}
+				j += size
+			}
+			w := s[i:j]
+			if rest := strings.TrimLeft(s[j:], " "); w == "chan" && strings.HasPrefix(rest, "<-") {
+				word("send")
+				j = len(s) - len(rest) + len("<-")
+			}
+			word(w)
+			i = j
+			continue
+		case strings.HasPrefix(s[i:], "<-"):
+			word("recv")
+		case strings.HasPrefix(s[i:], "[]"):
+			word("slice")
+		case r == '[' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1])):
+			word("array")
+		}
+		i += size
+	}
+
+	if b.Len() == 0 {
+		return "type"
+	}
+	return b.String()
+}
+
+// isIdentifier gets whether s is a valid Go identifier.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !isAlphaNumeric(r) || i == 0 && unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitTitle splits a specific type of format `<Title>:<Type>` into its
+// title and type.
+func splitTitle(s string) (title, typ string, ok bool) {
+	if sepIdx := strings.Index(s, ":"); sepIdx >= 0 && isIdentifier(s[:sepIdx]) {
+		return s[:sepIdx], s[sepIdx+1:], true
+	}
+	return "", s, false
+}
+
 // typify gets type name from string.
 // if string contains ":" then right part is returned otherwise string itself is returned
 func typify(s string) string {
-	if sepIdx := strings.Index(s, ":"); sepIdx >= 0 {
-		return s[sepIdx+1:]
-	}
-	return s
+	_, typ, _ := splitTitle(s)
+	return typ
+}
+
+// substitutionOrder gets the generic types of a type set in the order they
+// are substituted: longest names first, so that a generic which contains
+// another one, such as KeyType and Key, is replaced as a whole, and names of
+// the same length alphabetically, so that the output is the same every time.
+func substitutionOrder(typeSet map[string]string) []string {
+	generics := make([]string, 0, len(typeSet))
+	for generic := range typeSet {
+		generics = append(generics, generic)
+	}
+	sort.Slice(generics, func(i, j int) bool {
+		if len(generics[i]) != len(generics[j]) {
+			return len(generics[i]) > len(generics[j])
+		}
+		return generics[i] < generics[j]
+	})
+	return generics
 }
 
 func changePackage(r io.Reader, pkgName string) []byte {
@@ -610,6 +806,9 @@ func generateSpecificType(fs *token.FileSet, file *ast.File, spec replaceSpec) {
 						// MyStruct{ field: genericVal }
 						// MyStruct{ genericVal: field }
 						newIdent = transformType(v, spec, "KEY VALUE EXPR")
+					case *ast.Ellipsis:
+						// func a(g ...generic)
+						newIdent = transformType(v, spec, "ELLIPSIS")
 					case *ast.BranchStmt:
 						// ignore
 					case *ast.TypeAssertExpr:
@@ -658,8 +857,7 @@ func isGenericTypeDefinition(typeSpec *ast.TypeSpec) bool {
 		return isGenericTypeSelector(t)
 	case *ast.InterfaceType:
 		for _, field := range t.Methods.List {
-			// TODO: need to check new specific type also implements the other methods in the
-			// interface?
+			// The other methods of the interface are verified by checkConstraints.
 			if selector, ok := field.Type.(*ast.SelectorExpr); ok {
 				if isGenericTypeSelector(selector) {
 					return true
@@ -671,9 +869,21 @@ func isGenericTypeDefinition(typeSpec *ast.TypeSpec) bool {
 }
 
 func isGenericTypeSelector(selector *ast.SelectorExpr) bool {
-	if ident, ok := selector.X.(*ast.Ident); ok {
-		if ident.Name == "generic" &&
-			(selector.Sel.Name == "Type" || selector.Sel.Name == "Number") {
+	if ident, ok := selector.X.(*ast.Ident); ok && ident.Name == genericPackage {
+		for _, marker := range markerTypes {
+			if selector.Sel.Name == marker {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsMarker returns whether the line refers to one of the marker types,
+// such as generic.Type or generic.Number.
+func containsMarker(line string) bool {
+	for _, marker := range markerTypes {
+		if strings.Contains(line, genericPackage+"."+marker) {
 			return true
 		}
 	}
@@ -689,7 +899,7 @@ func generateSpecificAst(filename string, in io.ReadSeeker, typeSet map[string]s
 	fs := token.NewFileSet()
 	file, err := parser.ParseFile(fs, filename, in, parser.ParseComments)
 	if err != nil {
-		return nil, &errSource{Err: err}
+		return nil, sourceError(err)
 	}
 
 	// make sure every generic.Type is represented in the types
@@ -707,7 +917,7 @@ func generateSpecificAst(filename string, in io.ReadSeeker, typeSet map[string]s
 					if name, ok := tt.X.(*ast.Ident); ok {
 						if name.Name == genericPackage {
 							if _, ok := typeSet[ts.Name.Name]; !ok {
-								return nil, &errMissingSpecificType{GenericType: ts.Name.Name}
+								return nil, &MissingSpecificTypeError{GenericType: ts.Name.Name, Pos: fs.Position(ts.Pos()), TypeSet: typeSet}
 							}
 						}
 					}
@@ -717,8 +927,8 @@ func generateSpecificAst(filename string, in io.ReadSeeker, typeSet map[string]s
 	}
 
 	var buf bytes.Buffer
-	for t, specificType := range typeSet {
-		generateSpecificType(fs, file, replaceSpec{t, specificType})
+	for _, t := range substitutionOrder(typeSet) {
+		generateSpecificType(fs, file, replaceSpec{t, typeSet[t]})
 	}
 
 	err = printer.Fprint(&buf, fs, file)
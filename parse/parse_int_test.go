@@ -1,6 +1,8 @@
 package parse
 
 import (
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +35,33 @@ func TestWordify(t *testing.T) {
 	}
 
 }
+
+func TestSubIntoLiteral(t *testing.T) {
+
+	// titled specific types substitute their type rather than their title
+	assert.Equal(t, "[]string", subIntoLiteral("", "Something", "Something", "Words:[]string"))
+	assert.Equal(t, "[]int", subIntoLiteral("", "Something", "Something", "[]int"))
+
+	source, err := ioutil.ReadFile("test/queue/generic_queue.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Generics("generic_queue.go", "", strings.NewReader(string(source)), []map[string]string{{"Something": "Words:[]string"}}, nil, "", false)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(out), "type WordsQueue struct")
+		assert.Contains(t, string(out), "items [][]string")
+	}
+
+}
+
+func TestEllipsis(t *testing.T) {
+
+	source := "package queue\n\nimport \"github.com/kelindar/genny/generic\"\n\ntype Something generic.Type\n\nfunc Sum(items ...Something) []Something {\n\treturn items\n}\n"
+	for _, useAst := range []bool{false, true} {
+		out, err := Generics("generic_queue.go", "", strings.NewReader(source), []map[string]string{{"Something": "int"}}, nil, "", useAst)
+		if assert.NoError(t, err) {
+			assert.Contains(t, string(out), "func Sum(items ...int) []int {")
+		}
+	}
+
+}
@@ -18,6 +18,7 @@ var tests = []struct {
 	in       string
 	tag      string
 	imports  []string
+	tests    bool
 	types    []map[string]string
 
 	// expectations
@@ -26,6 +27,7 @@ var tests = []struct {
 
 	suppressForAstImpl    bool
 	suppressForLegacyImpl bool
+	suppressForTypesImpl  bool
 }{
 	{
 		filename:    "generic_queue.go",
@@ -86,10 +88,19 @@ var tests = []struct {
 		expectedOut: `test/numbers/int_number.go`,
 	},
 	{
-		filename:    "generic_digraph.go",
-		in:          `test/bugreports/generic_digraph.go`,
-		types:       []map[string]string{{"Node": "int"}},
-		expectedOut: `test/bugreports/int_digraph.go`,
+		filename:             "generic_digraph.go",
+		in:                   `test/bugreports/generic_digraph.go`,
+		types:                []map[string]string{{"Node": "int"}},
+		expectedOut:          `test/bugreports/int_digraph.go`,
+		suppressForTypesImpl: true,
+	},
+	{
+		filename:              "generic_digraph.go",
+		in:                    `test/bugreports/generic_digraph.go`,
+		types:                 []map[string]string{{"Node": "int"}},
+		expectedOut:           `test/bugreports/int_digraph_types.go.nobuild`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
 	},
 	{
 		filename:    "renamed_pkg.go",
@@ -138,6 +149,18 @@ var tests = []struct {
 		expectedOut:           `test/syntax/syntax_expected.go`,
 		tag:                   "",
 		suppressForLegacyImpl: true,
+		suppressForTypesImpl:  true,
+	},
+	{
+		filename: "syntax.go",
+		in:       `test/syntax/syntax.go`,
+		types: []map[string]string{
+			{"myType": "timeSpan:time.Duration"},
+			{"myType": "Fractional:float64"},
+		},
+		expectedOut:           `test/syntax/syntax_expected_types.go.nobuild`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
 	},
 	{
 		filename: "generic.go",
@@ -155,10 +178,19 @@ var tests = []struct {
 		expectedOut: `test/bugreports/int_new_and_make_slice.go`,
 	},
 	{
-		filename:    "cell_x.go",
-		in:          `test/bugreports/cell_x.go`,
-		types:       []map[string]string{{"X": "int"}},
-		expectedOut: `test/bugreports/cell_int.go`,
+		filename:             "cell_x.go",
+		in:                   `test/bugreports/cell_x.go`,
+		types:                []map[string]string{{"X": "int"}},
+		expectedOut:          `test/bugreports/cell_int.go`,
+		suppressForTypesImpl: true,
+	},
+	{
+		filename:              "cell_x.go",
+		in:                    `test/bugreports/cell_x.go`,
+		types:                 []map[string]string{{"X": "int"}},
+		expectedOut:           `test/bugreports/cell_int_types.go.nobuild`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
 	},
 	{
 		filename:    "interface_generic_type.go",
@@ -180,48 +212,119 @@ var tests = []struct {
 			{"TA": "string", "TB": "float64"},
 			{"TA": "string", "TB": "bool"},
 		},
-		expectedOut: `test/bugreports/receiver_expected.go`,
+		expectedOut:          `test/bugreports/receiver_expected.go`,
+		suppressForTypesImpl: true,
+	},
+	{
+		filename: "receiver_generic.go.nobuild",
+		in:       `test/bugreports/receiver_generic.go.nobuild`,
+		types: []map[string]string{
+			{"TA": "string", "TB": "int"},
+			{"TA": "string", "TB": "float64"},
+			{"TA": "string", "TB": "bool"},
+		},
+		expectedOut:           `test/bugreports/receiver_expected_types.go.nobuild`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
+	},
+	{
+		filename:    "generic_markers.go",
+		in:          `test/markers/generic_markers.go`,
+		types:       []map[string]string{{"Key": "string", "Value": "int", "Count": "uint8", "Ratio": "float32"}},
+		expectedOut: `test/markers/string_int_markers.go`,
+	},
+	{
+		filename:              "unrelated_generic.go",
+		in:                    `test/bugreports/unrelated_generic.go`,
+		types:                 []map[string]string{{"Something": "int"}},
+		expectedOut:           `test/bugreports/unrelated_int.go`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
+	},
+	{
+		filename:    "generic_queue_test.go",
+		in:          `test/tests/generic_queue_tests.go`,
+		tests:       true,
+		types:       []map[string]string{{"Something": "int"}, {"Something": "string"}},
+		expectedOut: `test/tests/int_queue_tests.go`,
+		// the types engine needs the template the tests refer to
+		suppressForTypesImpl: true,
+	},
+	{
+		filename:    "generic_index.go",
+		in:          `test/overlapping/generic_index.go`,
+		types:       []map[string]string{{"Key": "string", "KeyType": "int", "Item": "float64", "ItemList": "[]float64"}},
+		expectedOut: `test/overlapping/string_float64_index.go`,
+	},
+	{
+		filename:              "lru_generic.go",
+		in:                    `../examples/lru/lru_generic.go`,
+		types:                 []map[string]string{{"Key": "string", "CachedValue": "int"}},
+		expectedOut:           `test/lru/string_int_lru.go`,
+		suppressForAstImpl:    true,
+		suppressForLegacyImpl: true,
 	},
 }
 
 func TestParse(t *testing.T) {
 	for testNo, test := range tests {
 
-		for _, useAst := range []bool{true, false} {
-			if (useAst && test.suppressForAstImpl) || (!useAst && test.suppressForLegacyImpl) {
+		for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy, parse.EngineTypes} {
+			if (engine == parse.EngineAst && test.suppressForAstImpl) ||
+				(engine == parse.EngineLegacy && test.suppressForLegacyImpl) ||
+				(engine == parse.EngineTypes && test.suppressForTypesImpl) {
 				continue
 			}
-			t.Run(fmt.Sprintf("%d:%s/(ast:%v)", testNo, test.expectedOut, useAst), func(t *testing.T) {
+			t.Run(fmt.Sprintf("%d:%s/(%v)", testNo, test.expectedOut, engine), func(t *testing.T) {
 				in := contents(test.in)
-				expectedOut := contents(test.expectedOut)
+				bytes, err := parse.NewGenerator(parse.Options{Engine: engine, PkgName: test.pkgName, Imports: test.imports, StripTag: test.tag, Tests: test.tests}).Generate(test.filename, strings.NewReader(in), test.types)
+				assertParsed(t, testNo, test.filename, test.expectedOut, test.expectedErr, bytes, err)
+			})
+		}
 
-				bytes, err := parse.Generics(
-					test.filename,
-					test.pkgName,
-					strings.NewReader(in),
-					test.types,
-					test.imports,
-					test.tag,
-					useAst)
+	}
+
+}
 
-				// check the error
-				if test.expectedErr == nil {
-					assert.NoError(t, err, "(%d: %s) No error was expected but got: %s", testNo, test.filename, err)
-				} else {
-					assert.NotNil(t, err, "(%d: %s) No error was returned by one was expected: %s", testNo, test.filename, test.expectedErr)
-					assert.IsType(t, test.expectedErr, err, "(%d: %s) Generate should return object of type %v", testNo, test.filename, test.expectedErr)
-				}
+// TestParseGenerics runs the tests through the original API, which has no
+// types engine and does not prepare test files.
+func TestParseGenerics(t *testing.T) {
+	for testNo, test := range tests {
+		if test.tests {
+			continue
+		}
 
-				// assert the response
-				if !assert.Equal(t, expectedOut, string(bytes), "Parse didn't generate the expected output.") {
-					log.Println("EXPECTED: " + expectedOut)
-					log.Println("ACTUAL: " + string(bytes))
-				}
+		for _, engine := range []parse.Engine{parse.EngineAst, parse.EngineLegacy} {
+			if (engine == parse.EngineAst && test.suppressForAstImpl) ||
+				(engine == parse.EngineLegacy && test.suppressForLegacyImpl) {
+				continue
+			}
+			t.Run(fmt.Sprintf("%d:%s/(%v)", testNo, test.expectedOut, engine), func(t *testing.T) {
+				in := contents(test.in)
+				bytes, err := parse.Generics(test.filename, test.pkgName, strings.NewReader(in), test.types, test.imports, test.tag, engine == parse.EngineAst)
+				assertParsed(t, testNo, test.filename, test.expectedOut, test.expectedErr, bytes, err)
 			})
 		}
+	}
+}
+
+// assertParsed asserts the output and error of a test of the table.
+func assertParsed(t *testing.T, testNo int, filename, expectedOutFile string, expectedErr error, bytes []byte, err error) {
+	expectedOut := contents(expectedOutFile)
 
+	// check the error
+	if expectedErr == nil {
+		assert.NoError(t, err, "(%d: %s) No error was expected but got: %s", testNo, filename, err)
+	} else {
+		assert.NotNil(t, err, "(%d: %s) No error was returned by one was expected: %s", testNo, filename, expectedErr)
+		assert.IsType(t, expectedErr, err, "(%d: %s) Generate should return object of type %v", testNo, filename, expectedErr)
 	}
 
+	// assert the response
+	if !assert.Equal(t, expectedOut, string(bytes), "Parse didn't generate the expected output.") {
+		log.Println("EXPECTED: " + expectedOut)
+		log.Println("ACTUAL: " + string(bytes))
+	}
 }
 
 func contents(s string) string {
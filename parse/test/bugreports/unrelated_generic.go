@@ -0,0 +1,20 @@
+package bugreports
+
+import "github.com/kelindar/genny/generic"
+
+// Something is the generic type used in tests
+type Something generic.Type
+
+// HolderSomething holds a value, along with fields which merely share the
+// spelling of the generic type and must be left alone by the types engine.
+type HolderSomething struct {
+	Value         Something
+	somethingElse int
+	SomethingType string
+}
+
+// CountSomething returns the number of other values.
+func (h *HolderSomething) CountSomething() int {
+	somethingCount := h.somethingElse + len(h.SomethingType)
+	return somethingCount
+}
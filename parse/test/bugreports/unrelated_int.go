@@ -0,0 +1,18 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package bugreports
+
+// HolderInt holds a value, along with fields which merely share the
+// spelling of the generic type and must be left alone by the types engine.
+type HolderInt struct {
+	Value         int
+	somethingElse int
+	SomethingType string
+}
+
+// CountInt returns the number of other values.
+func (h *HolderInt) CountInt() int {
+	somethingCount := h.somethingElse + len(h.SomethingType)
+	return somethingCount
+}
@@ -0,0 +1,10 @@
+package check
+
+import "github.com/kelindar/genny/generic"
+
+type Something generic.Type
+
+// SomethingEqual returns whether a equals b.
+func SomethingEqual(a, b Something) bool {
+	return a == b
+}
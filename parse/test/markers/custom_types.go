@@ -0,0 +1,5 @@
+package markers
+
+type Score int32
+
+type Names []string
@@ -0,0 +1,26 @@
+package markers
+
+import "github.com/kelindar/genny/generic"
+
+type Key generic.Comparable
+
+type Value generic.Ordered
+
+type Count generic.Integer
+
+type Ratio generic.Float
+
+// KeyValueIndex keeps the largest Value seen for each Key.
+type KeyValueIndex map[Key]Value
+
+// Put records v for k, if it is larger than the current one.
+func (idx KeyValueIndex) Put(k Key, v Value) {
+	if current, ok := idx[k]; !ok || v > current {
+		idx[k] = v
+	}
+}
+
+// CountRatio divides a by b.
+func CountRatio(a, b Count) Ratio {
+	return Ratio(a%b) / Ratio(b)
+}
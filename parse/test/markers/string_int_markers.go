@@ -0,0 +1,19 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package markers
+
+// StringIntIndex keeps the largest int seen for each string.
+type StringIntIndex map[string]int
+
+// Put records v for k, if it is larger than the current one.
+func (idx StringIntIndex) Put(k string, v int) {
+	if current, ok := idx[k]; !ok || v > current {
+		idx[k] = v
+	}
+}
+
+// Uint8Float32 divides a by b.
+func Uint8Float32(a, b uint8) float32 {
+	return float32(a%b) / float32(b)
+}
@@ -0,0 +1,5 @@
+package numbers
+
+type MyFloat float64
+
+type MyName string
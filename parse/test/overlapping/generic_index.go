@@ -0,0 +1,30 @@
+package overlapping
+
+import "github.com/kelindar/genny/generic"
+
+type Key generic.Type
+type KeyType generic.Type
+type Item generic.Type
+type ItemList generic.Type
+
+// KeyItemIndex finds the ItemList of a Key, grouped by KeyType.
+type KeyItemIndex struct {
+	kinds map[Key]KeyType
+	lists map[KeyType]ItemList
+}
+
+// ItemListOf gets the ItemList of the KeyType of a Key.
+func (i *KeyItemIndex) ItemListOf(k Key) ItemList {
+	return i.lists[i.kinds[k]]
+}
+
+// KeyTypeOf gets the KeyType of a Key.
+func (i *KeyItemIndex) KeyTypeOf(k Key) KeyType {
+	return i.kinds[k]
+}
+
+// AddItem records the ItemList and the Item of a Key.
+func (i *KeyItemIndex) AddItem(k Key, kind KeyType, list ItemList, value Item) {
+	i.kinds[k] = kind
+	i.lists[kind] = list
+}
@@ -0,0 +1,26 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package overlapping
+
+// StringFloat64Index finds the []float64 of a string, grouped by int.
+type StringFloat64Index struct {
+	kinds map[string]int
+	lists map[int][]float64
+}
+
+// SliceFloat64Of gets the []float64 of the int of a string.
+func (i *StringFloat64Index) SliceFloat64Of(k string) []float64 {
+	return i.lists[i.kinds[k]]
+}
+
+// IntOf gets the int of a string.
+func (i *StringFloat64Index) IntOf(k string) int {
+	return i.kinds[k]
+}
+
+// AddFloat64 records the []float64 and the float64 of a string.
+func (i *StringFloat64Index) AddFloat64(k string, kind int, list []float64, value float64) {
+	i.kinds[k] = kind
+	i.lists[kind] = list
+}
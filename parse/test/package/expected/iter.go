@@ -0,0 +1,32 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package tree
+
+// EachInt calls fn for every value of the tree, in order.
+func (t *IntTree) EachInt(fn func(int)) {
+	walkInt(t.root, fn)
+}
+
+func walkInt(n *intNode, fn func(int)) {
+	if n == nil {
+		return
+	}
+	walkInt(n.left, fn)
+	fn(n.value)
+	walkInt(n.right, fn)
+}
+
+// EachString calls fn for every value of the tree, in order.
+func (t *StringTree) EachString(fn func(string)) {
+	walkString(t.root, fn)
+}
+
+func walkString(n *stringNode, fn func(string)) {
+	if n == nil {
+		return
+	}
+	walkString(n.left, fn)
+	fn(n.value)
+	walkString(n.right, fn)
+}
@@ -0,0 +1,72 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package tree
+
+// IntTree is a binary search tree of int values.
+type IntTree struct {
+	root *intNode
+	size int
+}
+
+type intNode struct {
+	value       int
+	left, right *intNode
+}
+
+// Insert adds the value to the tree.
+func (t *IntTree) Insert(value int) {
+	t.root = insertInt(t.root, value)
+	t.size++
+}
+
+// Len returns the number of values in the tree.
+func (t *IntTree) Len() int {
+	return t.size
+}
+
+func insertInt(n *intNode, value int) *intNode {
+	if n == nil {
+		return &intNode{value: value}
+	}
+	if value < n.value {
+		n.left = insertInt(n.left, value)
+	} else {
+		n.right = insertInt(n.right, value)
+	}
+	return n
+}
+
+// StringTree is a binary search tree of string values.
+type StringTree struct {
+	root *stringNode
+	size int
+}
+
+type stringNode struct {
+	value       string
+	left, right *stringNode
+}
+
+// Insert adds the value to the tree.
+func (t *StringTree) Insert(value string) {
+	t.root = insertString(t.root, value)
+	t.size++
+}
+
+// Len returns the number of values in the tree.
+func (t *StringTree) Len() int {
+	return t.size
+}
+
+func insertString(n *stringNode, value string) *stringNode {
+	if n == nil {
+		return &stringNode{value: value}
+	}
+	if value < n.value {
+		n.left = insertString(n.left, value)
+	} else {
+		n.right = insertString(n.right, value)
+	}
+	return n
+}
@@ -0,0 +1,15 @@
+package tree
+
+// EachItem calls fn for every value of the tree, in order.
+func (t *ItemTree) EachItem(fn func(Item)) {
+	walkItem(t.root, fn)
+}
+
+func walkItem(n *itemNode, fn func(Item)) {
+	if n == nil {
+		return
+	}
+	walkItem(n.left, fn)
+	fn(n.value)
+	walkItem(n.right, fn)
+}
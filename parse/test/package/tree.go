@@ -0,0 +1,39 @@
+package tree
+
+import "github.com/kelindar/genny/generic"
+
+type Item generic.Ordered
+
+// ItemTree is a binary search tree of Item values.
+type ItemTree struct {
+	root *itemNode
+	size int
+}
+
+type itemNode struct {
+	value       Item
+	left, right *itemNode
+}
+
+// Insert adds the value to the tree.
+func (t *ItemTree) Insert(value Item) {
+	t.root = insertItem(t.root, value)
+	t.size++
+}
+
+// Len returns the number of values in the tree.
+func (t *ItemTree) Len() int {
+	return t.size
+}
+
+func insertItem(n *itemNode, value Item) *itemNode {
+	if n == nil {
+		return &itemNode{value: value}
+	}
+	if value < n.value {
+		n.left = insertItem(n.left, value)
+	} else {
+		n.right = insertItem(n.right, value)
+	}
+	return n
+}
@@ -0,0 +1,23 @@
+package tree
+
+import "testing"
+
+func TestItemTree(t *testing.T) {
+	tree := new(ItemTree)
+	tree.Insert(2)
+	tree.Insert(1)
+
+	var values []Item
+	tree.EachItem(func(v Item) {
+		values = append(values, v)
+	})
+	if tree.Len() != 2 || len(values) != 2 || values[0] != 1 {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if new(ItemTree).Len() != 0 {
+		t.Fatal("expected an empty tree")
+	}
+}
@@ -0,0 +1,20 @@
+package tests
+
+import "github.com/kelindar/genny/generic"
+
+type Something generic.Type
+
+// SomethingQueue is a queue of Somethings.
+type SomethingQueue struct {
+	items []Something
+}
+
+// NewSomethingQueue makes a new empty queue.
+func NewSomethingQueue() *SomethingQueue {
+	return &SomethingQueue{}
+}
+
+// Len gets the number of items in the queue.
+func (q *SomethingQueue) Len() int {
+	return len(q.items)
+}
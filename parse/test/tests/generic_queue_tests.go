@@ -0,0 +1,38 @@
+package tests
+
+import "testing"
+
+var sizes = []int{0, 1, 2}
+
+func newQueue(t *testing.T) *SomethingQueue {
+	t.Helper()
+	return NewSomethingQueue()
+}
+
+func TestNew(t *testing.T) {
+	if newQueue(t) == nil {
+		t.Fatal("expected a queue")
+	}
+}
+
+func TestSomethingQueueLen(t *testing.T) {
+	for range sizes {
+		if n := NewSomethingQueue().Len(); n != 0 {
+			t.Fatalf("expected an empty queue, got %d items", n)
+		}
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewSomethingQueue()
+	}
+}
+
+func ExampleNewSomethingQueue() {
+	println(NewSomethingQueue().Len())
+}
+
+func Example() {
+	println(len(sizes))
+}
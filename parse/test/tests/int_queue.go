@@ -0,0 +1,34 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package tests
+
+// IntQueue is a queue of Ints.
+type IntQueue struct {
+	items []int
+}
+
+// NewIntQueue makes a new empty queue.
+func NewIntQueue() *IntQueue {
+	return &IntQueue{}
+}
+
+// Len gets the number of items in the queue.
+func (q *IntQueue) Len() int {
+	return len(q.items)
+}
+
+// StringQueue is a queue of Strings.
+type StringQueue struct {
+	items []string
+}
+
+// NewStringQueue makes a new empty queue.
+func NewStringQueue() *StringQueue {
+	return &StringQueue{}
+}
+
+// Len gets the number of items in the queue.
+func (q *StringQueue) Len() int {
+	return len(q.items)
+}
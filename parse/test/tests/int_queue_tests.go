@@ -0,0 +1,78 @@
+// Code generated with https://github.com/kelindar/genny DO NOT EDIT.
+// Any changes will be lost if this file is regenerated.
+
+package tests
+
+import (
+	"testing"
+)
+
+var sizesInt = []int{0, 1, 2}
+
+func newQueueInt(t *testing.T) *IntQueue {
+	t.Helper()
+	return NewIntQueue()
+}
+
+func TestNewInt(t *testing.T) {
+	if newQueueInt(t) == nil {
+		t.Fatal("expected a queue")
+	}
+}
+
+func TestIntQueueLen(t *testing.T) {
+	for range sizesInt {
+		if n := NewIntQueue().Len(); n != 0 {
+			t.Fatalf("expected an empty queue, got %d items", n)
+		}
+	}
+}
+
+func BenchmarkNewInt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewIntQueue()
+	}
+}
+
+func ExampleNewIntQueue() {
+	println(NewIntQueue().Len())
+}
+
+func Example_int() {
+	println(len(sizesInt))
+}
+
+var sizesString = []int{0, 1, 2}
+
+func newQueueString(t *testing.T) *StringQueue {
+	t.Helper()
+	return NewStringQueue()
+}
+
+func TestNewString(t *testing.T) {
+	if newQueueString(t) == nil {
+		t.Fatal("expected a queue")
+	}
+}
+
+func TestStringQueueLen(t *testing.T) {
+	for range sizesString {
+		if n := NewStringQueue().Len(); n != 0 {
+			t.Fatalf("expected an empty queue, got %d items", n)
+		}
+	}
+}
+
+func BenchmarkNewString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewStringQueue()
+	}
+}
+
+func ExampleNewStringQueue() {
+	println(NewStringQueue().Len())
+}
+
+func Example_string() {
+	println(len(sizesString))
+}
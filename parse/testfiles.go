@@ -0,0 +1,114 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// isTestFile returns whether the file is a test file, whose declarations are
+// made specific to every type set if Options.Tests is set.
+func isTestFile(filename string) bool {
+	return strings.HasSuffix(filename, "_test.go")
+}
+
+// prepareTestFile makes the package level declarations of a test file
+// specific to the type set they are generated for. Declarations which are
+// named after a generic type are renamed along with it, such as
+// TestSomethingQueue which becomes TestIntQueue, while the names of the other
+// declarations get the specific types appended, so that TestNew becomes
+// TestNewInt and a helper such as newFixture becomes newFixtureInt rather
+// than being declared once for every type set. Examples get the specific
+// types as their suffix, such as ExampleNew_int, to keep the names go test
+// expects. The package name is kept in sync for external test packages.
+func prepareTestFile(filename, pkgName string, in io.ReadSeeker, typeSet map[string]string) (io.ReadSeeker, string, error) {
+
+	// ensure we are at the beginning of the file
+	in.Seek(0, os.SEEK_SET)
+	source, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, "", sourceError(err)
+	}
+
+	fs := token.NewFileSet()
+	file, err := parser.ParseFile(fs, filename, source, 0)
+	if err != nil {
+		return nil, "", sourceError(err)
+	}
+	if pkgName != "" && strings.HasSuffix(file.Name.Name, "_test") && !strings.HasSuffix(pkgName, "_test") {
+		pkgName += "_test"
+	}
+
+	generics := genericNames([]map[string]string{typeSet})
+	suffix := ""
+	for _, generic := range generics {
+		suffix += wordify(typeSet[generic], true)
+	}
+
+	suffixes := make(map[*ast.Object]string)
+	for _, obj := range file.Scope.Objects {
+		if mentionsGeneric(obj.Name, generics) {
+			continue
+		}
+		suffixes[obj] = suffix
+		if strings.HasPrefix(obj.Name, "Example") && !strings.Contains(obj.Name, "_") {
+			suffixes[obj] = "_" + wordify(suffix, false)
+		}
+	}
+
+	// insert the suffix after every identifier which refers to a package level
+	// declaration, from the end of the file so that the offsets remain valid
+	type insertion struct {
+		offset int
+		suffix string
+	}
+	var insertions []insertion
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Obj != nil {
+			if suffix, ok := suffixes[ident.Obj]; ok {
+				insertions = append(insertions, insertion{fs.Position(ident.End()).Offset, suffix})
+			}
+		}
+		return true
+	})
+	sort.Slice(insertions, func(i, j int) bool { return insertions[i].offset > insertions[j].offset })
+
+	out := source
+	for _, insertion := range insertions {
+		var buf bytes.Buffer
+		buf.Write(out[:insertion.offset])
+		buf.WriteString(insertion.suffix)
+		buf.Write(out[insertion.offset:])
+		out = buf.Bytes()
+	}
+	return bytes.NewReader(out), pkgName, nil
+}
+
+// mentionsGeneric returns whether the identifier is named after one of the
+// generic types, in which case it is renamed by the substitution.
+func mentionsGeneric(name string, generics []string) bool {
+	for _, generic := range generics {
+		if indexBoundary(name, generic) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// genericNames gets the sorted generic types of the type sets.
+func genericNames(typeSets []map[string]string) []string {
+	var names stringArraySet
+	for _, typeSet := range typeSets {
+		for generic := range typeSet {
+			names = names.append(generic)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
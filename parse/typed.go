@@ -0,0 +1,228 @@
+package parse
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// typedTemplate is a template which has been type-checked, so that uses of
+// the generic types can be told apart from identifiers which merely happen to
+// share their spelling.
+type typedTemplate struct {
+	fset     *token.FileSet
+	pkg      *types.Package
+	info     *types.Info
+	generics map[types.Object]*ast.TypeSpec
+
+	// specs are the declarations of the generic types, in the order they
+	// are declared in
+	specs []*ast.TypeSpec
+}
+
+// newTypedTemplate type-checks the files of a template. Type errors are not
+// fatal: templates may refer to declarations in files which are not part of
+// the check, and such identifiers are simply left unresolved.
+func newTypedTemplate(fs *token.FileSet, files []*ast.File) *typedTemplate {
+	t := &typedTemplate{
+		fset: fs,
+		info: &types.Info{
+			Defs: make(map[*ast.Ident]types.Object),
+			Uses: make(map[*ast.Ident]types.Object),
+		},
+		generics: make(map[types.Object]*ast.TypeSpec),
+	}
+
+	conf := types.Config{
+		Importer: sharedImporter,
+		Error:    func(error) {},
+	}
+	t.pkg, _ = conf.Check(files[0].Name.Name, fs, files, t.info)
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && t.isGenericDefinition(ts) {
+					if obj := t.info.Defs[ts.Name]; obj != nil {
+						t.generics[obj] = ts
+						t.specs = append(t.specs, ts)
+					}
+				}
+			}
+		}
+	}
+	return t
+}
+
+// isGenericDefinition returns whether the type spec declares a generic type,
+// either directly or by embedding a generic type in an interface.
+func (t *typedTemplate) isGenericDefinition(spec *ast.TypeSpec) bool {
+	switch v := spec.Type.(type) {
+	case *ast.SelectorExpr:
+		return t.isGenericSelector(v)
+	case *ast.InterfaceType:
+		for _, field := range v.Methods.List {
+			if sel, ok := field.Type.(*ast.SelectorExpr); ok && len(field.Names) == 0 && t.isGenericSelector(sel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGenericSelector returns whether the selector refers to one of the marker
+// types of the generic package. If the selector could not be resolved, it
+// falls back to the spelling used by the other engines.
+func (t *typedTemplate) isGenericSelector(sel *ast.SelectorExpr) bool {
+	if obj := t.info.Uses[sel.Sel]; obj != nil {
+		return isGenericObject(obj)
+	}
+	return isGenericTypeSelector(sel)
+}
+
+// isGenericObject returns whether the object is declared in the generic package.
+func isGenericObject(obj types.Object) bool {
+	return obj.Pkg() != nil && isGenericImport(obj.Pkg().Path())
+}
+
+// checkSpecifics makes sure every generic type is represented in the type set,
+// reporting the first one declared which is not.
+func (t *typedTemplate) checkSpecifics(typeSet map[string]string) error {
+	for _, spec := range t.specs {
+		if _, ok := typeSet[spec.Name.Name]; !ok {
+			return &MissingSpecificTypeError{GenericType: spec.Name.Name, Pos: t.fset.Position(spec.Pos()), TypeSet: typeSet}
+		}
+	}
+	return nil
+}
+
+// isDerived returns whether an object named after a generic type should be
+// renamed. Only package level declarations and methods are renamed, so that
+// every type set produces distinct declarations, while fields, parameters and
+// locals keep their names.
+func (t *typedTemplate) isDerived(obj types.Object) bool {
+	switch obj.(type) {
+	case *types.PkgName, *types.Label, *types.Builtin, *types.Nil:
+		return false
+	}
+	if t.pkg == nil || obj.Pkg() != t.pkg {
+		return false
+	}
+	_, ok := obj.(*types.Func)
+	return ok || obj.Parent() == t.pkg.Scope()
+}
+
+// rewriteIdent substitutes the specific types into the identifier, in the
+// order of the generics.
+func (t *typedTemplate) rewriteIdent(ident *ast.Ident, typeSet map[string]string, generics []string) {
+	obj := t.info.Uses[ident]
+	if obj == nil {
+		obj = t.info.Defs[ident]
+	}
+
+	if spec, ok := t.generics[obj]; ok {
+		ident.Name = typify(typeSet[spec.Name.Name])
+		return
+	}
+
+	// Unresolved identifiers are left alone, since there is no telling what
+	// they refer to.
+	if obj == nil || !t.isDerived(obj) {
+		return
+	}
+
+	name := ident.Name
+	for _, generic := range generics {
+		specificType := typeSet[generic]
+		if containsFold(name, generic) {
+			name = replaceBoundaryFunc(name, generic, func(match string) string {
+				return wordify(specificType, unicode.IsUpper(rune(match[0])))
+			})
+		}
+	}
+	ident.Name = name
+}
+
+// rewrite substitutes the specific types into the file and removes the
+// declarations of the generic types.
+func (t *typedTemplate) rewrite(file *ast.File, typeSet map[string]string) {
+	generics := substitutionOrder(typeSet)
+	astutil.Apply(file,
+		func(c *astutil.Cursor) bool {
+			switch v := c.Node().(type) {
+			case *ast.File:
+				for _, commentGroup := range v.Comments {
+					for _, cmt := range commentGroup.List {
+						for _, generic := range generics {
+							cmt.Text = transformText(cmt.Text, replaceSpec{generic, typeSet[generic]})
+						}
+					}
+				}
+			case *ast.TypeSpec:
+				if obj := t.info.Defs[v.Name]; obj != nil && t.generics[obj] == v {
+					deleteAllComments(file, v)
+					c.Delete()
+					return false
+				}
+			case *ast.Ident:
+				if _, ok := c.Parent().(*ast.File); !ok {
+					t.rewriteIdent(v, typeSet, generics)
+				}
+			}
+			return true
+		},
+		func(c *astutil.Cursor) bool {
+			if v, ok := c.Node().(*ast.GenDecl); ok && len(v.Specs) == 0 {
+				deleteComment(file, v.Doc)
+				c.Delete()
+			}
+			return true
+		})
+}
+
+// generateSpecificTyped generates the specific code for a single type set
+// using the type information of the template. The siblings are type-checked
+// along with the file, but only the file itself is rewritten.
+func generateSpecificTyped(filename string, in io.ReadSeeker, typeSet map[string]string, siblings []templateFile) ([]byte, error) {
+
+	// ensure we are at the beginning of the file
+	in.Seek(0, os.SEEK_SET)
+
+	// parse the source file
+	fs := token.NewFileSet()
+	file, err := parser.ParseFile(fs, filename, in, parser.ParseComments)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+
+	files := []*ast.File{file}
+	for _, sibling := range siblings {
+		parsed, err := parser.ParseFile(fs, sibling.name, sibling.source, 0)
+		if err != nil {
+			return nil, sourceError(err)
+		}
+		files = append(files, parsed)
+	}
+
+	t := newTypedTemplate(fs, files)
+	if err := t.checkSpecifics(typeSet); err != nil {
+		return nil, err
+	}
+	t.rewrite(file, typeSet)
+
+	var buf bytes.Buffer
+	err = printer.Fprint(&buf, fs, file)
+	return buf.Bytes(), err
+}
@@ -1,6 +1,15 @@
 package parse
 
-import "strings"
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
 
 const (
 	typeSep     = " "
@@ -21,70 +30,520 @@ const (
 //     Person=man,woman Animal=dog,cat
 //     Person=man,woman,child Animal=dog,cat Place=london,paris
 //     Place=London:city.London
+//     Key=map[string]int,func(a, b int) bool Value=chan<- int
+//     Value="struct{ x int }",'chan<- int',chan<-\ int
+//     Key,Value=int:bool;string:float64
+//
+// Specific types may contain spaces and commas inside brackets, and may be
+// quoted with double or single quotes. A backslash escapes the character
+// following it, and words which are not followed by "=" are part of the
+// preceding specific type, so that "func() error" needs no quotes.
+//
+// Every combination of the specific types of different generics is
+// generated, unless the generics are listed together: "Key,Value=" is
+// followed by tuples of specific types separated by ";", whose types are
+// separated by ":", and generates only these tuples. Types with a title
+// must be quoted inside tuples, such as "Key,Value='Name:pkg.Name':int".
+//
+// The specific types are sets, which "&" intersects and "-" subtracts from,
+// such as "Key=BUILTINS-error,uintptr" for every builtin type but error and
+// uintptr, or "Key=BUILTINS&NUMBERS". "-" applies to every type which
+// follows it. "Key!=Value" drops the combinations in which Key and Value
+// are the same type.
+//
+// Keywords such as BUILTINS and NUMBERS stand for predefined groups of
+// specific types (see Groups).
 func TypeSet(arg string) ([]map[string]string, error) {
+	return Groups(nil).TypeSet(arg)
+}
 
-	types := make(map[string][]string)
-	var keys []string
-	for _, pair := range strings.Split(arg, typeSep) {
-		segs := strings.Split(pair, keyValueSep)
-		if len(segs) != 2 {
-			return nil, &errBadTypeArgs{Arg: arg, Message: "Generic=Specific expected"}
-		}
-		key := segs[0]
-		keys = append(keys, key)
-		types[key] = make([]string, 0)
-		for _, t := range strings.Split(segs[1], valuesSep) {
-			if t == builtins {
-				types[key] = append(types[key], Builtins...)
-			} else if t == numbers {
-				types[key] = append(types[key], Numbers...)
-			} else {
-				types[key] = append(types[key], t)
-			}
-		}
+// TypeSet turns a type string into type sets like the TypeSet function,
+// with the groups in addition to the predefined ones.
+func (g Groups) TypeSet(arg string) ([]map[string]string, error) {
+	e, err := g.expansion(arg)
+	if err != nil {
+		return nil, err
 	}
 
-	cursors := make(map[string]int)
-	for _, key := range keys {
-		cursors[key] = 0
+	typeSets := e.typeSets()
+	if len(typeSets) == 0 {
+		return nil, &TypeArgsError{Arg: arg, Message: "no combination of specific types is left"}
 	}
+	return typeSets, nil
+
+}
 
-	outChan := make(chan map[string]string)
-	go func() {
-		buildTypeSet(keys, 0, cursors, types, outChan)
-		close(outChan)
-	}()
+// TypeSets turns several type strings into the type sets of all of them, in
+// order, such as the type sets of "Key=int Value=bool" followed by those of
+// "Key=string Value=float64". Every type string must specify the same
+// generics.
+func TypeSets(args ...string) ([]map[string]string, error) {
+	return Groups(nil).TypeSets(args...)
+}
 
+// TypeSets turns several type strings into type sets like the TypeSets
+// function, with the groups in addition to the predefined ones.
+func (g Groups) TypeSets(args ...string) ([]map[string]string, error) {
 	var typeSets []map[string]string
-	for typeSet := range outChan {
-		typeSets = append(typeSets, typeSet)
+	for _, arg := range args {
+		sets, err := g.TypeSet(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(typeSets) > 0 && !sameGenerics(typeSets[0], sets[0]) {
+			return nil, &TypeArgsError{Arg: arg, Message: "the generics differ from those of " + strconv.Quote(args[0])}
+		}
+		typeSets = append(typeSets, sets...)
+	}
+	if len(typeSets) == 0 {
+		return nil, &TypeArgsError{Message: "Generic=Specific expected"}
 	}
-
 	return typeSets, nil
+}
+
+// expansion parses the type string into the groups whose combinations are
+// its type sets.
+func (g Groups) expansion(arg string) (*expansion, error) {
+	tokens, err := tokenizeTypes(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &expansion{generics: make(map[string]string)}
+	for i := 0; i < len(tokens); {
+		if isDistinct(tokens, i) {
+			e.distinct = append(e.distinct, [2]string{tokens[i].text, tokens[i+2].text})
+			i += 3
+			continue
+		}
+
+		var generics []string
+		if generics, i = readGenerics(tokens, i); generics == nil {
+			return nil, &TypeArgsError{Arg: arg, Message: "Generic=Specific expected"}
+		}
+		for _, generic := range generics {
+			if _, ok := e.generics[generic]; ok {
+				return nil, &TypeArgsError{Arg: arg, Message: "generic " + generic + " is specified more than once"}
+			}
+			e.generics[generic] = ""
+		}
+
+		// several generics list tuples separated by ";"
+		var group []map[string]string
+		for ; ; i++ {
+			var tuple [][]map[string]string
+			if tuple, i, err = g.readTuple(arg, tokens, i, generics); err != nil {
+				return nil, err
+			}
+			group = append(group, (&expansion{groups: tuple}).typeSets()...)
 
+			if len(generics) == 1 || i >= len(tokens) || tokens[i].kind != tuplesToken {
+				break
+			}
+		}
+		e.groups = append(e.groups, group)
+	}
+	if len(e.groups) == 0 {
+		return nil, &TypeArgsError{Arg: arg, Message: "Generic=Specific expected"}
+	}
+	for _, generics := range e.distinct {
+		_, ok0 := e.generics[generics[0]]
+		_, ok1 := e.generics[generics[1]]
+		if !ok0 || !ok1 {
+			return nil, &TypeArgsError{Arg: arg, Message: generics[0] + "!=" + generics[1] + " refers to an unknown generic"}
+		}
+	}
+	return e, nil
+}
+
+// readGenerics reads the generics starting at tokens[i], such as "Key=" or
+// "Key,Value=", and gets the index of the token following them. The
+// generics are nil if tokens[i] does not start a list of generics.
+func readGenerics(tokens []typeToken, i int) ([]string, int) {
+	var generics []string
+	for ; i < len(tokens) && tokens[i].kind == wordToken && isIdentifier(tokens[i].text); i += 2 {
+		generics = append(generics, tokens[i].text)
+		if i+1 < len(tokens) && tokens[i+1].kind == keyValueToken {
+			return generics, i + 2
+		}
+		if i+1 >= len(tokens) || tokens[i+1].kind != valuesToken {
+			break
+		}
+	}
+	return nil, i
+}
+
+// readTuple reads a specific type for every generic starting at tokens[i],
+// separated by ":", and gets the index of the token following them. Every
+// specific type is a group of single-generic type sets, as keywords such as
+// BUILTINS expand to several types.
+func (g Groups) readTuple(arg string, tokens []typeToken, i int, generics []string) ([][]map[string]string, int, error) {
+	tuple := make([][]map[string]string, len(generics))
+	for j, generic := range generics {
+		if j > 0 {
+			if i >= len(tokens) || tokens[i].kind != tupleToken {
+				return nil, i, &TypeArgsError{Arg: arg, Message: strconv.Itoa(len(generics)) + " specific types expected for " +
+					strings.Join(generics, valuesSep)}
+			}
+			i++
+		}
+
+		var (
+			specifics []string
+			err       error
+		)
+		if specifics, i, err = g.readSpecifics(arg, tokens, i, len(generics) == 1); err != nil {
+			return nil, i, err
+		}
+		for _, specific := range specifics {
+			tuple[j] = append(tuple[j], map[string]string{generic: specific})
+		}
+	}
+	if i < len(tokens) && tokens[i].kind == tupleToken {
+		return nil, i, &TypeArgsError{Arg: arg, Message: strconv.Itoa(len(generics)) + " specific types expected for " +
+			strings.Join(generics, valuesSep)}
+	}
+	return tuple, i, nil
 }
 
-func buildTypeSet(keys []string, keyI int, cursors map[string]int, types map[string][]string, out chan<- map[string]string) {
-	key := keys[keyI]
-	for cursors[key] < len(types[key]) {
-		if keyI < len(keys)-1 {
-			buildTypeSet(keys, keyI+1, copycursors(cursors), types, out)
+// readSpecifics reads the set of specific types starting at tokens[i], and
+// gets the index of the token following it. The types and keywords of the
+// set are separated by ",", intersected by "&" and subtracted by "-", "&"
+// taking precedence over ",", and "," over "-".
+func (g Groups) readSpecifics(arg string, tokens []typeToken, i int, titled bool) ([]string, int, error) {
+	var (
+		sets [2][]string // the types included and excluded
+		set  int         // the index of the set being read
+		term []string    // the intersection being read
+		op   = valuesToken
+	)
+	for ; ; i++ {
+		var t string
+		if t, i = specificType(tokens, i, titled); t == "" {
+			return nil, i, &TypeArgsError{Arg: arg, Message: "Generic=Specific expected"}
+		}
+
+		specifics, ok := g.lookup(t)
+		if _, typ, _ := splitTitle(t); !ok && !isTypeExpr(typ) {
+			return nil, i, &TypeArgsError{Arg: arg, Message: strconv.Quote(t) + " is not a type"}
+		} else if !ok {
+			specifics = []string{t}
+		}
+		if op == intersectToken {
+			term = intersectTypes(term, specifics)
 		} else {
-			// build the typeset for this combination
-			ts := make(map[string]string)
-			for k, vals := range types {
-				ts[k] = vals[cursors[k]]
+			term = specifics
+		}
+
+		op = -1
+		if i < len(tokens) {
+			op = tokens[i].kind
+		}
+		if op != intersectToken {
+			sets[set] = unionTypes(sets[set], term)
+		}
+		switch op {
+		case exceptToken:
+			set = 1
+		case valuesToken, intersectToken:
+		default:
+			specifics = subtractTypes(sets[0], sets[1])
+			if len(specifics) == 0 {
+				return nil, i, &TypeArgsError{Arg: arg, Message: "no specific type is left"}
 			}
-			out <- ts
+			return specifics, i, nil
 		}
-		cursors[key]++
 	}
 }
 
-func copycursors(source map[string]int) map[string]int {
-	copy := make(map[string]int)
-	for k, v := range source {
-		copy[k] = v
+// unionTypes gets the types of a followed by those of b which are not in a.
+func unionTypes(a, b []string) []string {
+	union := append([]string(nil), a...)
+	return append(union, subtractTypes(b, a)...)
+}
+
+// intersectTypes gets the types of a which are also in b.
+func intersectTypes(a, b []string) []string {
+	var intersection []string
+	for _, t := range a {
+		if containsType(b, t) {
+			intersection = append(intersection, t)
+		}
+	}
+	return intersection
+}
+
+// subtractTypes gets the types of a which are not in b.
+func subtractTypes(a, b []string) []string {
+	var difference []string
+	for _, t := range a {
+		if !containsType(b, t) && !containsType(difference, t) {
+			difference = append(difference, t)
+		}
+	}
+	return difference
+}
+
+// containsType gets whether types contains t, ignoring their titles.
+func containsType(types []string, t string) bool {
+	for _, typ := range types {
+		if typify(typ) == typify(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDistinct gets whether tokens[i] starts a predicate such as "Key!=Value".
+func isDistinct(tokens []typeToken, i int) bool {
+	return i+2 < len(tokens) && tokens[i].kind == wordToken && tokens[i+1].kind == distinctToken &&
+		tokens[i+2].kind == wordToken
+}
+
+// isDistinctTypeSet gets whether each pair of generics has different
+// specific types in the type set.
+func isDistinctTypeSet(typeSet map[string]string, distinct [][2]string) bool {
+	for _, generics := range distinct {
+		if canonicalType(typeSet[generics[0]]) == canonicalType(typeSet[generics[1]]) {
+			return false
+		}
+	}
+	return true
+}
+
+// typeAliases are the predeclared aliases and the types they stand for.
+var typeAliases = map[string]string{
+	"byte": "uint8",
+	"rune": "int32",
+}
+
+// canonicalType gets the same spelling for identical specific types, such as
+// "[]uint8" for both []byte and []uint8, ignoring their titles and spacing.
+func canonicalType(s string) string {
+	src := []byte(typify(s))
+	var sc scanner.Scanner
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+
+	var b strings.Builder
+	for {
+		_, tok, lit := sc.Scan()
+		switch {
+		case tok == token.EOF:
+			return b.String()
+		case tok == token.SEMICOLON && lit == "\n":
+			continue
+		case tok == token.IDENT && typeAliases[lit] != "":
+			lit = typeAliases[lit]
+		case lit == "":
+			lit = tok.String()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(lit)
+	}
+}
+
+// specificType reads the specific type starting at tokens[i], joining the
+// words which do not start a list of generics with a space, and gets the
+// index of the token following it. A titled specific type, such as
+// "London:city.London", is only read if titled is set, as ":" otherwise
+// separates the types of a tuple.
+func specificType(tokens []typeToken, i int, titled bool) (string, int) {
+	var (
+		b    strings.Builder
+		word bool // whether the last token read was a word
+	)
+	for ; i < len(tokens); i++ {
+		switch token := tokens[i]; {
+		case token.kind == wordToken:
+			if b.Len() > 0 {
+				if generics, _ := readGenerics(tokens, i); generics != nil || isDistinct(tokens, i) {
+					return b.String(), i
+				}
+			}
+			if word {
+				b.WriteString(typeSep)
+			}
+			b.WriteString(token.text)
+			word = true
+		case token.kind == tupleToken && titled && b.Len() > 0:
+			b.WriteString(token.text)
+			word = false
+		default:
+			return b.String(), i
+		}
+	}
+	return b.String(), i
+}
+
+// isTypeExpr gets whether s can be parsed as a Go type. It is parsed as the
+// type of a variable, since other expressions such as a|b are not types. A
+// single literal such as 1 is accepted as well, as earlier versions did.
+func isTypeExpr(s string) bool {
+	if expr, err := parser.ParseExpr(s); err == nil {
+		if _, ok := expr.(*ast.BasicLit); ok {
+			return true
+		}
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\nvar _ "+s, parser.ParseComments)
+	if err != nil || len(file.Decls) != 1 || len(file.Comments) != 0 {
+		return false
+	}
+	decl, ok := file.Decls[0].(*ast.GenDecl)
+	if !ok || len(decl.Specs) != 1 {
+		return false
+	}
+	spec, ok := decl.Specs[0].(*ast.ValueSpec)
+	return ok && spec.Type != nil && len(spec.Values) == 0
+}
+
+// sameGenerics gets whether two type sets are for the same generics.
+func sameGenerics(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for generic := range a {
+		if _, ok := b[generic]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type typeTokenKind int
+
+const (
+	wordToken     typeTokenKind = iota // a generic or a specific type
+	keyValueToken                      // "="
+	valuesToken                        // ","
+	tupleToken                         // ":"
+	tuplesToken                        // ";"
+	intersectToken                     // "&"
+	exceptToken                        // "-"
+	distinctToken                      // "!="
+	notToken                           // "!", which is only valid in "!="
+)
+
+type typeToken struct {
+	kind typeTokenKind
+	text string
+}
+
+// tokenizeTypes splits the type string into words and the "=", ",", ":",
+// ";", "&", "-" and "!=" tokens, skipping whitespace. The "-" of a channel
+// direction is part of the word. Separators inside brackets or quotes do not
+// split words; quotes and escapes are removed, except inside brackets, where
+// they are part of the type (such as the tags of a struct).
+func tokenizeTypes(arg string) ([]typeToken, error) {
+	var (
+		tokens  []typeToken
+		word    strings.Builder
+		inWord  bool
+		closers []rune // the closing brackets expected, innermost last
+		quote   rune   // the quote being read, if any
+		escaped bool
+		prev    rune // the previous character
+	)
+	endWord := func() {
+		if inWord {
+			tokens = append(tokens, typeToken{kind: wordToken, text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for _, r := range arg {
+		nested := len(closers) > 0
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '`':
+			if nested {
+				word.WriteRune(r)
+			}
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			if nested || quote != 0 {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'' || nested && r == '`':
+			if nested {
+				word.WriteRune(r)
+			}
+			quote, inWord = r, true
+		case r == '(' || r == '[' || r == '{':
+			closers = append(closers, map[rune]rune{'(': ')', '[': ']', '{': '}'}[r])
+			word.WriteRune(r)
+			inWord = true
+		case r == ')' || r == ']' || r == '}':
+			if !nested || closers[len(closers)-1] != r {
+				return nil, &TypeArgsError{Arg: arg, Message: "unbalanced " + string(r)}
+			}
+			closers = closers[:len(closers)-1]
+			word.WriteRune(r)
+		case nested:
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			endWord()
+		case r == '=' && prev == '!' && len(tokens) > 0 && tokens[len(tokens)-1].kind == notToken:
+			tokens[len(tokens)-1] = typeToken{kind: distinctToken, text: "!="}
+		case r == '!':
+			endWord()
+			tokens = append(tokens, typeToken{kind: notToken, text: string(r)})
+		case r == '=' || r == ',' || r == ':' || r == ';' || r == '&' || r == '-' && prev != '<':
+			endWord()
+			kind := map[rune]typeTokenKind{'=': keyValueToken, ',': valuesToken, ':': tupleToken, ';': tuplesToken,
+				'&': intersectToken, '-': exceptToken}[r]
+			tokens = append(tokens, typeToken{kind: kind, text: string(r)})
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+		prev = r
+	}
+
+	switch {
+	case escaped:
+		return nil, &TypeArgsError{Arg: arg, Message: "trailing \\"}
+	case quote != 0:
+		return nil, &TypeArgsError{Arg: arg, Message: "unterminated " + string(quote)}
+	case len(closers) > 0:
+		return nil, &TypeArgsError{Arg: arg, Message: "missing " + string(closers[len(closers)-1])}
+	}
+	endWord()
+	return tokens, nil
+}
+
+// FormatTypeSet turns a single type set back into its string form, with the
+// generic types in alphabetical order.
+func FormatTypeSet(typeSet map[string]string) string {
+	pairs := make([]string, 0, len(typeSet))
+	for generic, specific := range typeSet {
+		pairs = append(pairs, generic+keyValueSep+specific)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, typeSep)
+}
+
+// FileSuffix gets a suffix for the name of the file generated for a single
+// type set, such as "int_string" for "Key=int Value=string", with the
+// specific types in the alphabetical order of their generics.
+func FileSuffix(typeSet map[string]string) string {
+	generics := make([]string, 0, len(typeSet))
+	for generic := range typeSet {
+		generics = append(generics, generic)
+	}
+	sort.Strings(generics)
+
+	words := make([]string, len(generics))
+	for i, generic := range generics {
+		words[i] = strings.ToLower(wordify(typeSet[generic], false))
 	}
-	return copy
+	return strings.Join(words, "_")
 }
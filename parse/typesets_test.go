@@ -1,6 +1,7 @@
 package parse_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/kelindar/genny/parse"
@@ -72,3 +73,184 @@ func TestArgsToTypeset(t *testing.T) {
 	}
 
 }
+
+func TestArgsToTypesetSyntax(t *testing.T) {
+	for arg, expected := range map[string][]map[string]string{
+		"  Person=man,woman   Animal=dog ": {
+			{"Person": "man", "Animal": "dog"},
+			{"Person": "woman", "Animal": "dog"},
+		},
+		"Key = string , int": {{"Key": "string"}, {"Key": "int"}},
+		"Key=map[string]int,Place:city.London": {
+			{"Key": "map[string]int"},
+			{"Key": "Place:city.London"},
+		},
+		"Less=func(a, b int) bool Value=chan<- int": {
+			{"Less": "func(a, b int) bool", "Value": "chan<- int"},
+		},
+		`Value="struct{ x int }",'chan<- int',<-chan\ int,Point:struct{ X int "json:\"x\"" }`: {
+			{"Value": "struct{ x int }"},
+			{"Value": "chan<- int"},
+			{"Value": "<-chan int"},
+			{"Value": `Point:struct{ X int "json:\"x\"" }`},
+		},
+	} {
+		ts, err := parse.TypeSet(arg)
+		if assert.NoError(t, err, arg) {
+			assert.Equal(t, expected, ts, arg)
+		}
+	}
+
+	for _, arg := range []string{
+		"",
+		"Person",
+		"Person=",
+		"Person=man,",
+		"Person=man Person=woman",
+		"Person=map[string]int]",
+		"Person=map[string",
+		"Person=\"man",
+		"Person=man\\",
+		"Person=man woman",
+		"Person=man=woman",
+		"Person Place=man",
+		"Person=a|b",
+		"Person=1+2",
+		"Person=\"int = 1\"",
+		"Person=\"int /* man */\"",
+	} {
+		_, err := parse.TypeSet(arg)
+		var argsErr *parse.TypeArgsError
+		assert.True(t, errors.As(err, &argsErr), "%s: %v", arg, err)
+	}
+}
+
+func TestArgsToTypesetTuples(t *testing.T) {
+	ts, err := parse.TypeSet("Key,Value=int:bool;string:float64")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Key": "int", "Value": "bool"},
+			{"Key": "string", "Value": "float64"},
+		}, ts)
+	}
+
+	// tuples are combined with the specific types of other generics
+	ts, err = parse.TypeSet("Kind=a,b Key,Value=int:'Flag:bool'; map[string]int : func(a, b int) bool")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Kind": "a", "Key": "int", "Value": "Flag:bool"},
+			{"Kind": "a", "Key": "map[string]int", "Value": "func(a, b int) bool"},
+			{"Kind": "b", "Key": "int", "Value": "Flag:bool"},
+			{"Kind": "b", "Key": "map[string]int", "Value": "func(a, b int) bool"},
+		}, ts)
+	}
+
+	ts, err = parse.TypeSet("Key,Value=NUMBERS:bool")
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(parse.Numbers), len(ts))
+	}
+
+	ts, err = parse.TypeSets("Key=int Value=bool", "Key,Value=string:float64")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Key": "int", "Value": "bool"},
+			{"Key": "string", "Value": "float64"},
+		}, ts)
+	}
+
+	for _, args := range [][]string{
+		{"Key,Value=int"},
+		{"Key,Value=int:bool:string"},
+		{"Key,Value=int:bool,string:float64"},
+		{"Key=int;string"},
+		{"Key,Key=int:bool"},
+		{"Key=int Value=bool", "Key=string"},
+		{},
+	} {
+		_, err := parse.TypeSets(args...)
+		var argsErr *parse.TypeArgsError
+		assert.True(t, errors.As(err, &argsErr), "%v: %v", args, err)
+	}
+}
+
+func TestArgsToTypesetAlgebra(t *testing.T) {
+	for arg, expected := range map[string][]string{
+		"Key=BUILTINS-error,uintptr":           subtract(parse.Builtins, "error", "uintptr"),
+		"Key=string,BUILTINS - NUMBERS":        append([]string{"string"}, subtract(subtract(parse.Builtins, parse.Numbers...), "string")...),
+		"Key=BUILTINS&NUMBERS-float32,float64": subtract(parse.Numbers, "float32", "float64"),
+		"Key=int,string,int,Name:string":       {"int", "string"},
+		"Key=Things:[]Thing,bool-[]Thing":      {"bool"},
+		"Key=chan<- int,<-chan int-<-chan int": {"chan<- int"},
+		"Key=int&string,bool":                  {"bool"},
+		"Key=int - string Value=bool":          {"int"},
+	} {
+		ts, err := parse.TypeSet(arg)
+		if !assert.NoError(t, err, arg) {
+			continue
+		}
+		var actual []string
+		for _, typeSet := range ts {
+			actual = append(actual, typeSet["Key"])
+		}
+		assert.Equal(t, expected, actual, arg)
+	}
+
+	ts, err := parse.TypeSet("Key=int,string Value=int,string,bool Key!=Value")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Key": "int", "Value": "string"},
+			{"Key": "int", "Value": "bool"},
+			{"Key": "string", "Value": "int"},
+			{"Key": "string", "Value": "bool"},
+		}, ts)
+	}
+
+	// byte and rune are the same types as uint8 and int32
+	ts, err = parse.TypeSet("Key,Value=BUILTINS-error:BUILTINS-error Key != Value")
+	if assert.NoError(t, err) {
+		assert.Equal(t, (len(parse.Builtins)-1)*(len(parse.Builtins)-2)-4, len(ts))
+		for _, typeSet := range ts {
+			pair := typeSet["Key"] + " " + typeSet["Value"]
+			assert.NotContains(t, []string{"byte uint8", "uint8 byte", "rune int32", "int32 rune"}, pair)
+		}
+	}
+	ts, err = parse.TypeSet("Key=[]byte,map[rune]string Value=[]uint8,map[int32]string,Runes:[]rune Key!=Value")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []map[string]string{
+			{"Key": "[]byte", "Value": "map[int32]string"},
+			{"Key": "[]byte", "Value": "Runes:[]rune"},
+			{"Key": "map[rune]string", "Value": "[]uint8"},
+			{"Key": "map[rune]string", "Value": "Runes:[]rune"},
+		}, ts)
+	}
+
+	for _, arg := range []string{
+		"Key=int-int",
+		"Key=int-",
+		"Key=-int",
+		"Key=int&",
+		"Key=int Key!=Value",
+		"Key=int Value=int Key!=Value",
+		"Key=int Key!Value",
+		"Key=int Key!",
+	} {
+		_, err := parse.TypeSet(arg)
+		var argsErr *parse.TypeArgsError
+		assert.True(t, errors.As(err, &argsErr), "%s: %v", arg, err)
+	}
+}
+
+// subtract gets the types which are not excluded, in order.
+func subtract(types []string, excluded ...string) []string {
+	var difference []string
+	for _, t := range types {
+		keep := true
+		for _, e := range excluded {
+			keep = keep && t != e
+		}
+		if keep {
+			difference = append(difference, t)
+		}
+	}
+	return difference
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kelindar/genny/parse"
+)
+
+// regen regenerates the files in place, using the arguments recorded in
+// their genny:source blocks. The defaults provide the settings which are not
+// recorded, such as -check.
+func regen(fileNames []string, defaults options) (int, error) {
+	for _, fileName := range fileNames {
+		if code, err := regenFile(fileName, defaults); err != nil {
+			return code, err
+		}
+	}
+	return 0, nil
+}
+
+// regenFile regenerates a single file. If it was generated from a template
+// directory, every file of the package is regenerated.
+func regenFile(fileName string, defaults options) (int, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return exitcodeSourceFileInvalid, err
+	}
+	origin, err := parse.ReadOrigin(file)
+	file.Close()
+	if err != nil {
+		return exitcodeSourceFileInvalid, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	opts := defaults
+	opts.out, opts.source, opts.groups, opts.types = fileName, true, origin.Groups, origin.Types
+	opts.pkgName, opts.imports, opts.tag, opts.header = origin.Pkg, origin.Imports, origin.Tag, origin.Header
+	if origin.Engine != "" {
+		if opts.engine, err = parse.ParseEngine(origin.Engine); err != nil {
+			return exitcodeInvalidArgs, err
+		}
+	}
+
+	typeSets, err := parseTypes(origin.Groups, origin.Types)
+	if err != nil {
+		return exitcodeInvalidTypeSet, err
+	}
+
+	// the template is relative to the generated file, unless it is a module
+	dir := filepath.Dir(fileName)
+	in := filepath.Join(dir, filepath.FromSlash(origin.In))
+	if info, err := os.Stat(in); err == nil && info.IsDir() {
+		opts.out = dir
+		return generatePackage(in, typeSets, opts)
+	}
+	template, err := readTemplate(origin.In, dir)
+	if err != nil {
+		return exitcodeSourceFileInvalid, err
+	}
+	opts.version = template.Version
+
+	return generate(template.Name, bytes.NewReader(template.Source), typeSets, opts)
+}
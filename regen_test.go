@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kelindar/genny/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegen(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"templates/queue.go":     queueTemplate,
+		"templates/tree/tree.go": "package tree\n\nimport \"github.com/kelindar/genny/generic\"\n\ntype Item generic.Type\n\ntype ItemTree struct{ root *ItemNode }\n",
+		"templates/tree/node.go": "package tree\n\ntype ItemNode struct{ value Item }\n",
+		"gen/not_generated.go":   "package gen\n",
+	})
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "gen", "queue.go")
+	in := filepath.Join(dir, "templates", "queue.go")
+	file, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeSets, _ := parse.TypeSets("Something=int", "Something=string")
+	code, err := generate(in, file, typeSets, options{out: out, pkgName: "gen", header: "Code generated for {{.Types}}. DO NOT EDIT.", source: true, types: []string{"Something=int", "Something=string"}})
+	file.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	typeSets, _ = parse.TypeSet("Item=int")
+	code, err = generatePackage(filepath.Join(dir, "templates", "tree"), typeSets, options{out: filepath.Join(dir, "gen", "tree"), source: true, groups: []string{"ITEMS=int"}, types: []string{"Item=ITEMS"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	b, err := ioutil.ReadFile(out)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "// genny:source -in=\"../templates/queue.go\"\n")
+		assert.Contains(t, string(b), "// genny:source -types=\"Something=int\"\n// genny:source -types=\"Something=string\"\n")
+	}
+
+	// change the templates, then regenerate from the recorded arguments
+	changed := strings.Replace(queueTemplate, "items []Something", "items []Something\n\tsize  int", 1)
+	if err := ioutil.WriteFile(in, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "templates", "tree", "node.go"), []byte("package tree\n\ntype ItemNode struct{ value, other Item }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err = regen([]string{out, filepath.Join(dir, "gen", "tree", "node.go")}, options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	b, err = ioutil.ReadFile(out)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "// Code generated for Something=int; Something=string. DO NOT EDIT.\n")
+		assert.Contains(t, string(b), "package gen")
+		assert.Contains(t, string(b), "size  int")
+		assert.Contains(t, string(b), "type StringQueue struct")
+		assert.Contains(t, string(b), "// genny:source -in=\"../templates/queue.go\"\n")
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "gen", "tree", "node.go"))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(b), "value, other int")
+	}
+
+	code, err = regen([]string{filepath.Join(dir, "gen", "not_generated.go")}, options{})
+	assert.Error(t, err)
+	assert.Equal(t, exitcodeSourceFileInvalid, code)
+}
@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kelindar/genny/out"
+)
+
+// Cached keeps the templates fetched from a source in a local directory,
+// keyed by name and version, so that they are only fetched once. Templates
+// fetched without a version are never cached, since they change over time.
+type Cached struct {
+	Source Source
+	Dir    string
+}
+
+// Fetch gets the template from the cache, or from the source if it is not
+// cached yet.
+func (c Cached) Fetch(name, version string) (*Template, error) {
+	if version == "" {
+		return c.Source.Fetch(name, version)
+	}
+
+	path := filepath.Join(c.Dir, escapePath(version), filepath.FromSlash(escapePath(name)))
+	if b, err := ioutil.ReadFile(path); err == nil {
+		return &Template{Name: name, Version: version, Location: path, Source: b}, nil
+	}
+
+	template, err := c.Source.Fetch(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// a cache which cannot be written to only costs another download
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		f := &out.AtomicFile{FileName: path}
+		if _, err := f.Write(template.Source); err != nil {
+			f.Abort()
+		} else {
+			f.Close()
+		}
+	}
+	return template, nil
+}
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir is a local directory of templates, such as a checkout of a template
+// library. Directories are not versioned, so every version is served from
+// the same files.
+type Dir string
+
+// Fetch gets the template from the directory.
+func (d Dir) Fetch(name, version string) (*Template, error) {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	return readFile(name, version, path)
+}
+
+// File serves "file://" references, such as "file:///templates/queue.go",
+// straight from the file system.
+type File struct{}
+
+// Fetch gets the template if the name is a "file://" URL.
+func (File) Fetch(name, version string) (*Template, error) {
+	if !strings.HasPrefix(name, "file://") {
+		return nil, &NotFoundError{Name: name, Version: version}
+	}
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(name, version, filepath.FromSlash(u.Path))
+}
+
+// readFile reads the template from the path.
+func readFile(name, version, path string) (*Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, &NotFoundError{Name: name, Version: version}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Name: name, Version: version, Location: path, Source: b}, nil
+}
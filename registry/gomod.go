@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindRequirements reads the requirements of the go.mod file of the module
+// containing dir, looking in its parent directories. It returns nil if dir is
+// not inside a module.
+func FindRequirements(dir string) (map[string]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		switch {
+		case err == nil:
+			return ParseRequirements(b), nil
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// ParseRequirements gets the version of every module required by a go.mod
+// file, keyed by module path. Replacements are not taken into account.
+func ParseRequirements(gomod []byte) map[string]string {
+	requires := make(map[string]string)
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+
+		switch {
+		case inBlock && len(fields) == 1 && fields[0] == ")":
+			inBlock = false
+		case inBlock && len(fields) == 2:
+			requires[unquote(fields[0])] = fields[1]
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+		case len(fields) == 3 && fields[0] == "require":
+			requires[unquote(fields[1])] = fields[2]
+		}
+	}
+	return requires
+}
+
+// unquote removes the quotes go.mod allows around module paths.
+func unquote(path string) string {
+	return strings.Trim(path, "\"`")
+}
+
+// requiredVersion gets the version of the module containing the template
+// with the name, preferring the longest matching module path.
+func requiredVersion(requires map[string]string, name string) string {
+	module := ""
+	for path := range requires {
+		if strings.HasPrefix(name, path+"/") && len(path) > len(module) {
+			module = path
+		}
+	}
+	return requires[module]
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the timeout of the requests made by HTTP sources without
+// a client.
+const DefaultTimeout = 30 * time.Second
+
+// HTTP serves templates from a web server, such as the raw files of a git
+// repository, e.g. "https://github.com/me/templates/raw/{version}/".
+type HTTP struct {
+	// BaseURL is the URL the names are appended to. Any "{version}" in it is
+	// replaced with the requested version.
+	BaseURL string
+
+	// DefaultVersion is used when no version is requested, such as "master".
+	DefaultVersion string
+
+	// Client makes the requests, or nil for a client with DefaultTimeout.
+	Client *http.Client
+}
+
+// Fetch downloads the template.
+func (h HTTP) Fetch(name, version string) (*Template, error) {
+	v := version
+	if v == "" {
+		v = h.DefaultVersion
+	}
+	url := strings.Replace(h.BaseURL, "{version}", v, -1) + name
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &NotFoundError{Name: name, Version: version}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Name: name, Version: version, Location: url, Source: b}, nil
+}
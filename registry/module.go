@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Module serves templates from the Go module cache, for names which start
+// with a module path, such as "github.com/me/templates/queue/queue.go". The
+// module is never downloaded, so it must already be in the cache, e.g. with
+// go mod download.
+type Module struct {
+	// Cache is the module cache, or empty for $GOMODCACHE (which defaults to
+	// $GOPATH/pkg/mod).
+	Cache string
+
+	// Requires are the versions of the required modules, keyed by module
+	// path, which are used when no version is requested. See
+	// FindRequirements.
+	Requires map[string]string
+}
+
+// Fetch gets the template from the module which contains it, at the version
+// requested or required by Requires. The template has the resolved version.
+func (m Module) Fetch(name, version string) (*Template, error) {
+	if version == "" {
+		version = requiredVersion(m.Requires, name)
+	}
+
+	elems := strings.Split(name, "/")
+	if version == "" || len(elems) < 2 || !strings.Contains(elems[0], ".") {
+		return nil, &NotFoundError{Name: name, Version: version}
+	}
+
+	// the longest module path wins, as it does for the go command
+	cache := m.cache()
+	for i := len(elems) - 1; i > 0; i-- {
+		dir := filepath.Join(cache, escapePath(strings.Join(elems[:i], "/"))+"@"+escapePath(version))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return readFile(name, version, filepath.Join(dir, filepath.Join(elems[i:]...)))
+		}
+	}
+	return nil, &NotFoundError{Name: name, Version: version}
+}
+
+// cache gets the directory of the module cache.
+func (m Module) cache() string {
+	if m.Cache != "" {
+		return m.Cache
+	}
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+// escapePath escapes a module path or version the way the module cache does,
+// replacing every upper case letter with an exclamation mark followed by the
+// letter in lower case.
+func escapePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteRune('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+// Package registry locates the templates fetched by genny get, which may
+// live in local directories, in the Go module cache or behind a HTTP server.
+package registry
+
+import (
+	"strings"
+)
+
+// Template is a template found by a Source.
+type Template struct {
+	// Name is the name the template was requested with, without the version.
+	Name string
+
+	// Version is the version of the template, or empty if it is not known.
+	Version string
+
+	// Location describes where the template was found, such as its path.
+	Location string
+
+	// Source is the content of the template.
+	Source []byte
+}
+
+// Source finds templates by name.
+type Source interface {
+	// Fetch gets the template with the name, such as "queue/queue.go", at
+	// the version, which may be empty for the latest one. It returns a
+	// *NotFoundError if the source does not have the template.
+	Fetch(name, version string) (*Template, error)
+}
+
+// Chain is a list of sources which are tried in order, so that local
+// templates can override remote ones.
+type Chain []Source
+
+// Fetch gets the template from the first source which has it.
+func (c Chain) Fetch(name, version string) (*Template, error) {
+	for _, source := range c {
+		template, err := source.Fetch(name, version)
+		if _, ok := err.(*NotFoundError); ok {
+			continue
+		}
+		return template, err
+	}
+	return nil, &NotFoundError{Name: name, Version: version}
+}
+
+// SplitVersion splits a reference such as "queue/queue.go@v1.0.0" into the
+// name and the version, which is empty if the reference has none.
+func SplitVersion(ref string) (name, version string) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// NotFoundError represents an error when a template cannot be found.
+type NotFoundError struct {
+	Name    string
+	Version string
+}
+
+// Error gets a human readable string describing this error.
+func (e NotFoundError) Error() string {
+	if e.Version != "" {
+		return "Template '" + e.Name + "' not found at version " + e.Version
+	}
+	return "Template '" + e.Name + "' not found"
+}
@@ -0,0 +1,205 @@
+package registry_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kelindar/genny/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSplitVersion(t *testing.T) {
+	name, version := registry.SplitVersion("github.com/me/templates/queue.go@v1.2.0")
+	assert.Equal(t, "github.com/me/templates/queue.go", name)
+	assert.Equal(t, "v1.2.0", version)
+
+	name, version = registry.SplitVersion("queue/queue.go")
+	assert.Equal(t, "queue/queue.go", name)
+	assert.Equal(t, "", version)
+}
+
+func TestDirAndFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"queue/queue.go": "package queue\n"})
+	defer os.RemoveAll(dir)
+
+	template, err := registry.Dir(dir).Fetch("queue/queue.go", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package queue\n", string(template.Source))
+		assert.Equal(t, filepath.Join(dir, "queue", "queue.go"), template.Location)
+	}
+
+	template, err = registry.File{}.Fetch("file://"+filepath.ToSlash(filepath.Join(dir, "queue", "queue.go")), "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package queue\n", string(template.Source))
+	}
+
+	_, err = registry.Dir(dir).Fetch("queue/missing.go", "")
+	assert.IsType(t, &registry.NotFoundError{}, err)
+	_, err = registry.File{}.Fetch("queue/queue.go", "")
+	assert.IsType(t, &registry.NotFoundError{}, err)
+}
+
+func TestModule(t *testing.T) {
+	cache := writeFiles(t, map[string]string{
+		"github.com/!me/templates@v1.2.0/queue/queue.go": "package queue // v1.2.0\n",
+		"github.com/!me/templates/set@v0.1.0/set.go":     "package set\n",
+		"github.com/!me/templates@v1.3.0/queue/queue.go": "package queue // v1.3.0\n",
+		"github.com/!me/templates@v1.2.0/set/set.go":     "package set // not a module\n",
+	})
+	defer os.RemoveAll(cache)
+
+	m := registry.Module{Cache: cache}
+	template, err := m.Fetch("github.com/Me/templates/queue/queue.go", "v1.2.0")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package queue // v1.2.0\n", string(template.Source))
+		assert.Equal(t, "v1.2.0", template.Version)
+	}
+
+	// nested modules take precedence
+	template, err = m.Fetch("github.com/Me/templates/set/set.go", "v0.1.0")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package set\n", string(template.Source))
+	}
+
+	for _, version := range []string{"", "v2.0.0"} {
+		_, err = m.Fetch("github.com/Me/templates/queue/queue.go", version)
+		assert.IsType(t, &registry.NotFoundError{}, err, version)
+	}
+	_, err = m.Fetch("queue/queue.go", "v1.2.0")
+	assert.IsType(t, &registry.NotFoundError{}, err)
+}
+
+func TestHTTPCached(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/raw/master/queue/queue.go":
+			w.Write([]byte("package queue // master\n"))
+		case "/raw/v1.0.0/queue/queue.go":
+			w.Write([]byte("package queue // v1.0.0\n"))
+		case "/raw/master/broken.go":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := ioutil.TempDir("", "genny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+
+	source := registry.Cached{
+		Source: registry.HTTP{BaseURL: server.URL + "/raw/{version}/", DefaultVersion: "master"},
+		Dir:    cache,
+	}
+	for i := 0; i < 2; i++ {
+		template, err := source.Fetch("queue/queue.go", "v1.0.0")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "package queue // v1.0.0\n", string(template.Source))
+		}
+		template, err = source.Fetch("queue/queue.go", "")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "package queue // master\n", string(template.Source))
+		}
+	}
+
+	// the versioned template was only downloaded once
+	assert.Equal(t, 3, requests)
+
+	_, err = source.Fetch("queue/missing.go", "")
+	assert.IsType(t, &registry.NotFoundError{}, err)
+	_, err = source.Fetch("broken.go", "")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "500")
+	}
+}
+
+func TestChain(t *testing.T) {
+	local := writeFiles(t, map[string]string{"queue/queue.go": "package queue // local\n"})
+	defer os.RemoveAll(local)
+	other := writeFiles(t, map[string]string{"queue/queue.go": "package queue // other\n", "set/set.go": "package set\n"})
+	defer os.RemoveAll(other)
+
+	chain := registry.Chain{registry.Dir(local), registry.Dir(other)}
+	template, err := chain.Fetch("queue/queue.go", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package queue // local\n", string(template.Source))
+	}
+	template, err = chain.Fetch("set/set.go", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "package set\n", string(template.Source))
+	}
+
+	_, err = chain.Fetch("lru/lru.go", "v1.0.0")
+	assert.EqualError(t, err, "Template 'lru/lru.go' not found at version v1.0.0")
+}
+
+func TestRequirements(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod": `module github.com/me/app
+
+go 1.16
+
+require github.com/me/templates v1.2.0 // templates
+
+require (
+	"github.com/me/templates/set" v0.1.0
+	golang.org/x/tools v0.1.0 // indirect
+)
+
+replace golang.org/x/tools => ../tools
+`,
+		"cmd/app/main.go": "package main\n",
+	})
+	defer os.RemoveAll(dir)
+
+	requires, err := registry.FindRequirements(filepath.Join(dir, "cmd", "app"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{
+			"github.com/me/templates":     "v1.2.0",
+			"github.com/me/templates/set": "v0.1.0",
+			"golang.org/x/tools":          "v0.1.0",
+		}, requires)
+	}
+
+	cache := writeFiles(t, map[string]string{
+		"github.com/me/templates@v1.2.0/queue/queue.go": "package queue\n",
+		"github.com/me/templates/set@v0.1.0/set.go":     "package set\n",
+	})
+	defer os.RemoveAll(cache)
+
+	m := registry.Module{Cache: cache, Requires: requires}
+	for name, version := range map[string]string{
+		"github.com/me/templates/queue/queue.go": "v1.2.0",
+		"github.com/me/templates/set/set.go":     "v0.1.0",
+	} {
+		template, err := m.Fetch(name, "")
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, version, template.Version, name)
+		}
+	}
+}